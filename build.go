@@ -0,0 +1,278 @@
+package doc
+
+import (
+	"cmp"
+
+	"github.com/hackborn/doc/parser"
+)
+
+// ------------------------------------------------------------
+// OPERAND
+
+// Operand is the subject of a condition: a field, a function
+// call or a literal. Errors are kept until the condition is
+// compiled, so calls can be chained.
+type Operand struct {
+	node parser.AstNode
+	err  error
+}
+
+// Field answers an operand for the field at path,
+// in dot notation, i.e. "address.city" or "tags[0]".
+func Field(path string) Operand {
+	n, err := parser.NewField(path)
+	return Operand{node: n, err: err}
+}
+
+// Call answers an operand that calls the registered function
+// name. Each arg is an Operand or a literal; plain strings are
+// field names, as when parsed.
+func Call(name string, args ...any) Operand {
+	nodes := make([]parser.AstNode, 0, len(args))
+	for _, arg := range args {
+		var n parser.AstNode
+		var err error
+		if s, ok := arg.(string); ok {
+			n, err = parser.NewField(s)
+		} else {
+			n, err = operandNode(arg)
+		}
+		if err != nil {
+			return Operand{err: err}
+		}
+		nodes = append(nodes, n)
+	}
+	n, err := parser.NewCall(name, nodes...)
+	return Operand{node: n, err: err}
+}
+
+// Value answers an operand for a literal value.
+func Value(v any) Operand {
+	n, err := parser.NewValue(v)
+	return Operand{node: n, err: err}
+}
+
+// Neg answers the arithmetic negation of o.
+func (o Operand) Neg() Operand {
+	if o.err != nil {
+		return o
+	}
+	return Operand{node: parser.NewNeg(o.node)}
+}
+
+func (o Operand) Eq(v any) Cond {
+	return o.binary(EqualKeyword, v)
+}
+
+func (o Operand) Neq(v any) Cond {
+	return o.binary(NotEqualKeyword, v)
+}
+
+func (o Operand) Lt(v any) Cond {
+	return o.binary(LessKeyword, v)
+}
+
+func (o Operand) Lte(v any) Cond {
+	return o.binary(LessEqualKeyword, v)
+}
+
+func (o Operand) Gt(v any) Cond {
+	return o.binary(GreaterKeyword, v)
+}
+
+func (o Operand) Gte(v any) Cond {
+	return o.binary(GreaterEqualKeyword, v)
+}
+
+// Assign answers the assignment o = v.
+func (o Operand) Assign(v any) Cond {
+	return o.binary(AssignKeyword, v)
+}
+
+func (o Operand) In(values ...any) Cond {
+	return o.in(InKeyword, values)
+}
+
+func (o Operand) NotIn(values ...any) Cond {
+	return o.in(NotInKeyword, values)
+}
+
+func (o Operand) Like(pattern string) Cond {
+	return o.match(LikeKeyword, pattern)
+}
+
+func (o Operand) StartsWith(prefix string) Cond {
+	return o.match(StartsWithKeyword, prefix)
+}
+
+func (o Operand) Contains(s string) Cond {
+	return o.match(ContainsKeyword, s)
+}
+
+func (o Operand) Matches(pattern string) Cond {
+	return o.match(MatchesKeyword, pattern)
+}
+
+func (o Operand) IsNull() Cond {
+	return o.predicate(IsNullKeyword)
+}
+
+func (o Operand) IsNotNull() Cond {
+	return o.predicate(IsNotNullKeyword)
+}
+
+func (o Operand) Exists() Cond {
+	return o.predicate(ExistsKeyword)
+}
+
+// Between answers the inclusive range test lower <= o <= upper.
+func (o Operand) Between(lower, upper any) Cond {
+	return o.BetweenRange(Range{Lower: lower, Upper: upper})
+}
+
+// BetweenRange answers a range test with optionally exclusive bounds.
+func (o Operand) BetweenRange(r Range) Cond {
+	if o.err != nil {
+		return Cond{err: o.err}
+	}
+	bounds, err := operandNodes([]any{r.Lower, r.Upper})
+	if err != nil {
+		return Cond{err: err}
+	}
+	n, err := parser.NewBetween(o.node, bounds[0], bounds[1], r.LowerExclusive, r.UpperExclusive)
+	return Cond{node: n, err: err}
+}
+
+// Cond answers o itself as a condition, i.e. a boolean
+// field or function.
+func (o Operand) Cond() Cond {
+	return Cond{node: o.node, err: o.err}
+}
+
+func (o Operand) binary(keyword string, v any) Cond {
+	rhs, err := operandNode(v)
+	if o.err != nil || err != nil {
+		return Cond{err: cmp.Or(o.err, err)}
+	}
+	n, err := parser.NewBinary(keyword, o.node, rhs)
+	return Cond{node: n, err: err}
+}
+
+func (o Operand) in(keyword string, values []any) Cond {
+	items, err := operandNodes(values)
+	if o.err != nil || err != nil {
+		return Cond{err: cmp.Or(o.err, err)}
+	}
+	n, err := parser.NewBinary(keyword, o.node, parser.NewList(items...))
+	return Cond{node: n, err: err}
+}
+
+func (o Operand) match(keyword string, pattern string) Cond {
+	if o.err != nil {
+		return Cond{err: o.err}
+	}
+	rhs, err := parser.NewValue(pattern)
+	if err != nil {
+		return Cond{err: err}
+	}
+	n, err := parser.NewMatch(keyword, o.node, rhs)
+	return Cond{node: n, err: err}
+}
+
+func (o Operand) predicate(keyword string) Cond {
+	if o.err != nil {
+		return Cond{err: o.err}
+	}
+	n, err := parser.NewPredicate(keyword, o.node)
+	return Cond{node: n, err: err}
+}
+
+// ------------------------------------------------------------
+// COND
+
+// Cond is a condition built in code, i.e.
+// Field("age").Gte(21).And(Field("name").In("a", "b")).
+// It builds the same AST as parsing the equivalent string.
+// Cond is an Expr that uses the default formatting; use
+// DB.Build for the driver's formatting and validation.
+type Cond struct {
+	node parser.AstNode
+	err  error
+}
+
+func (c Cond) And(others ...Cond) Cond {
+	return c.join(AndKeyword, others)
+}
+
+func (c Cond) Or(others ...Cond) Cond {
+	return c.join(OrKeyword, others)
+}
+
+func (c Cond) Not() Cond {
+	if err := c.stateErr(); err != nil {
+		return Cond{err: err}
+	}
+	return Cond{node: parser.NewNot(c.node)}
+}
+
+func (c Cond) join(keyword string, others []Cond) Cond {
+	for _, other := range others {
+		if err := cmp.Or(c.stateErr(), other.stateErr()); err != nil {
+			return Cond{err: err}
+		}
+		n, err := parser.NewBinary(keyword, c.node, other.node)
+		c = Cond{node: n, err: err}
+	}
+	return c
+}
+
+func (c Cond) Compile() (Expr, error) {
+	return c.expr().Compile()
+}
+
+func (c Cond) Format() (string, error) {
+	return c.expr().Format()
+}
+
+func (c Cond) Extract(fn any) error {
+	return c.expr().Extract(fn)
+}
+
+// MarshalJSON answers me as a JSON document. See ExprToJSON().
+func (c Cond) MarshalJSON() ([]byte, error) {
+	return ExprToJSON(c)
+}
+
+func (c Cond) expr() *rawExpression {
+	return &rawExpression{ast: c.node, err: c.stateErr(), f: parser.DefaultFormat()}
+}
+
+func (c Cond) stateErr() error {
+	if c.err == nil && c.node == nil {
+		return &Error{Code: parser.BadRequestErrCode, Msg: "empty condition"}
+	}
+	return c.err
+}
+
+// ------------------------------------------------------------
+// SUPPORT
+
+// operandNode answers the AST for an Operand or literal.
+func operandNode(v any) (parser.AstNode, error) {
+	if o, ok := v.(Operand); ok {
+		return o.node, o.err
+	}
+	return parser.NewValue(v)
+}
+
+func operandNodes(values []any) ([]parser.AstNode, error) {
+	nodes := make([]parser.AstNode, 0, len(values))
+	for _, v := range values {
+		n, err := operandNode(v)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
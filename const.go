@@ -12,8 +12,29 @@ const (
 
 const (
 	// Expose parser-private data for the drivers.
-	AndKeyword    = parser.AndKeyword
-	AssignKeyword = parser.AssignKeyword
-	ListKeyword   = parser.ListKeyword
-	OrKeyword     = parser.OrKeyword
+	AndKeyword          = parser.AndKeyword
+	AssignKeyword       = parser.AssignKeyword
+	BetweenKeyword      = parser.BetweenKeyword
+	ContainsKeyword     = parser.ContainsKeyword
+	EqualKeyword        = parser.EqualKeyword
+	EscapeKeyword       = parser.EscapeKeyword
+	ExistsKeyword       = parser.ExistsKeyword
+	FalseKeyword        = parser.FalseKeyword
+	GreaterKeyword      = parser.GreaterKeyword
+	GreaterEqualKeyword = parser.GreaterEqualKeyword
+	InKeyword           = parser.InKeyword
+	IsNotNullKeyword    = parser.IsNotNullKeyword
+	IsNullKeyword       = parser.IsNullKeyword
+	LessKeyword         = parser.LessKeyword
+	LessEqualKeyword    = parser.LessEqualKeyword
+	LikeKeyword         = parser.LikeKeyword
+	ListKeyword         = parser.ListKeyword
+	MatchesKeyword      = parser.MatchesKeyword
+	NotEqualKeyword     = parser.NotEqualKeyword
+	NotInKeyword        = parser.NotInKeyword
+	NotKeyword          = parser.NotKeyword
+	NullKeyword         = parser.NullKeyword
+	OrKeyword           = parser.OrKeyword
+	StartsWithKeyword   = parser.StartsWithKeyword
+	TrueKeyword         = parser.TrueKeyword
 )
@@ -34,9 +34,21 @@ func (d *DB) Private(a any) error {
 }
 
 // Answer a new expression, using the optional validator and
-// the driver's formatting.
-func (d *DB) Expr(expr string, v Validator) Expr {
-	return &rawExpression{term: expr, v: v, f: d.format}
+// the driver's formatting. args are bound to any placeholders
+// (?, $1, :name) in the expression; use Named() for named placeholders.
+func (d *DB) Expr(expr string, v Validator, args ...any) Expr {
+	return d.ExprWith(expr, v, ParseOptions{}, args...)
+}
+
+// ExprWith is Expr, with opts controlling parsing.
+func (d *DB) ExprWith(expr string, v Validator, opts ParseOptions, args ...any) Expr {
+	return &rawExpression{term: expr, v: v, f: d.format, args: args, opts: opts}
+}
+
+// Build answers the condition c as an expression, using the
+// optional validator and the driver's formatting.
+func (d *DB) Build(c Cond, v Validator) Expr {
+	return &rawExpression{ast: c.node, err: c.stateErr(), v: v, f: d.format}
 }
 
 func Open(driverName, dataSourceName string) (*DB, error) {
@@ -0,0 +1,28 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// Names of the built-in SQL dialects.
+const (
+	MySQLDialect    = parser.MySQLDialect
+	PostgresDialect = parser.PostgresDialect
+	SQLiteDialect   = parser.SQLiteDialect
+)
+
+// RegisterDialect makes a format available by name,
+// for FormatWithDialect.
+func RegisterDialect(name string, f Format) {
+	parser.RegisterDialect(name, f)
+}
+
+// Dialect answers the registered format with the given name.
+func Dialect(name string) (Format, bool) {
+	return parser.LookupDialect(name)
+}
+
+// Dialects returns a sorted list of the names of the registered formats.
+func Dialects() []string {
+	return parser.Dialects()
+}
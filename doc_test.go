@@ -0,0 +1,483 @@
+package doc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hackborn/doc/parser"
+)
+
+// ---------------------------------------------------------
+// TEST-EXPR-FORMAT
+func TestExprFormat(t *testing.T) {
+	db := &DB{format: parser.DefaultFormat()}
+	table := []struct {
+		term    string
+		opts    ParseOptions
+		args    []any
+		want    string
+		wantErr error
+	}{
+		{"id = 10 AND form = wd20", ParseOptions{}, nil, "id = 10 AND form = wd20", nil},
+		{"id = 10 && form = wd20", ParseOptions{}, nil, "", ErrSyntax},
+		{"id = 10 && form = wd20", ParseOptions{Lenient: true}, nil, "form = wd20", nil},
+		{"id IN (1, 2, 3)", ParseOptions{MaxListItems: 2}, nil, "", ErrTooManyItems},
+		{"id = ? AND form = :form", ParseOptions{}, []any{10, Named("form", "wd20")}, "id = 10 AND form = wd20", nil},
+		{"id = ?", ParseOptions{}, []any{10, 11}, "", ErrBadRequest},
+		{"id = ? OR id = $2", ParseOptions{}, []any{10, 11}, "", ErrBadRequest},
+	}
+	for i, v := range table {
+		have, haveErr := db.ExprWith(v.term, nil, v.opts, v.args...).Format()
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestExprFormat %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestExprFormat %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestExprFormat %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-BUILD
+func TestBuild(t *testing.T) {
+	table := []struct {
+		cond    Cond
+		want    string
+		wantErr error
+	}{
+		{Field("age").Gte(21), "age >= 21", nil},
+		{Field("age").Gte(21).And(Field("name").In("a", "b")), "age >= 21 AND name IN (a, b)", nil},
+		{Field("a.b").Eq(Field("c")).Or(Field("d").Lt(1.5)).Not(), "NOT (a.b == c OR d < 1.5)", nil},
+		{Field("a").Eq(1).And(), "a == 1", nil},
+		{Field("age").Between(1, 2).And(Field("email").IsNull()), "age BETWEEN 1 AND 2 AND email IS NULL", nil},
+		{Call("len", "tags").Gt(2), "len(tags) > 2", nil},
+		{Field("a").Neq("x").Or(Field("b").Lte(2), Field("c").NotIn(1, 2)), "a != x OR b <= 2 OR c NOT IN (1, 2)", nil},
+		{Field("a").Like("x%").And(Field("b").StartsWith("y"), Field("c").Contains("z"), Field("d").Matches("^w")), "a LIKE x% AND b STARTSWITH y AND c CONTAINS z AND d MATCHES ^w", nil},
+		{Field("a").BetweenRange(Range{Lower: 1, Upper: 5, UpperExclusive: true}), "(a >= 1 AND a < 5)", nil},
+		{Field("a").Neg().Gt(Value(-1)).And(Field("b").IsNotNull(), Field("c").Exists()), "-a > -1 AND b IS NOT NULL AND EXISTS(c)", nil},
+		{Field("active").Cond().And(Call("lower", Field("name")).Eq("bob")), "active AND lower(name) == bob", nil},
+		{Call("nope", "a").Eq(1), "", ErrSyntax},
+		{Field("a").Between(1, struct{}{}), "", ErrBadRequest},
+		{Field("").Eq(1), "", ErrSyntax},
+		{Field("a[").Eq(1), "", ErrSyntax},
+		{Field("a").In(), "", ErrSyntax},
+		{Field("a").Eq(struct{}{}), "", ErrBadRequest},
+		{Field("a").In(1, struct{}{}), "", ErrBadRequest},
+		{Field("").Gte(1).And(Field("a").Eq(1)), "", ErrSyntax},
+		{Field("a").Eq(1).And(Field("").Gte(1)), "", ErrSyntax},
+		{Cond{}, "", ErrBadRequest},
+		{Cond{}.And(Field("a").Eq(1)), "", ErrBadRequest},
+		{Field("a").Eq(1).Or(Cond{}), "", ErrBadRequest},
+		{Cond{}.Not(), "", ErrBadRequest},
+	}
+	for i, v := range table {
+		have, haveErr := v.cond.Format()
+		extractErr := v.cond.Extract(nil)
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestBuild %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestBuild %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if v.wantErr != nil && !errors.Is(extractErr, v.wantErr) {
+			t.Fatalf("TestBuild %v has extract error %v but exptected %v", i, extractErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestBuild %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-DB-BUILD
+func TestDBBuild(t *testing.T) {
+	table := []struct {
+		dialect string
+		cond    Cond
+		v       Validator
+		want    string
+		wantErr error
+	}{
+		{PostgresDialect, Field("Name").Eq("it's").And(Field("Address.City").In("a", "b")), nil, `"Name" = 'it''s' AND "Address"->>'City' IN ('a', 'b')`, nil},
+		{MySQLDialect, Field("Name").Like("a%"), NewStructValidator[validatorPerson](), "`Name` LIKE 'a%' ESCAPE '\\\\'", nil},
+		{SQLiteDialect, Field("Tags[0]").Eq(1), nil, "", ErrUnsupported},
+	}
+	for i, v := range table {
+		f, _ := Dialect(v.dialect)
+		db := &DB{format: f}
+		have, haveErr := db.Build(v.cond, v.v).Format()
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestDBBuild %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestDBBuild %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestDBBuild %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-VALIDATOR
+func TestValidator(t *testing.T) {
+	table := []struct {
+		term string
+		want []string
+	}{
+		{`Name = "a" AND Address.City = "b"`, nil},
+		{`Tags[0] = "x" AND Meta.any = 1 AND Scores[1] > 2`, nil},
+		{`ID = 1 AND Created > 2`, nil},
+		{`ValidatorAudit.Created > 2`, nil},
+		{`secret = 1 AND Nope = 2`, []string{"secret", "Nope"}},
+		{`Address.Zip = 1 OR Tags.x = 1 OR Name[0] = 1`, []string{"Address.Zip", "Tags.x", "Name[0]"}},
+		{`Scores[2] = 1 OR validatorBase.ID = 1`, []string{"Scores[2]", "validatorBase.ID"}},
+	}
+	db := &DB{format: parser.DefaultFormat()}
+	v := NewStructValidator[validatorPerson]()
+	for i, tt := range table {
+		_, haveErr := db.Expr(tt.term, v).Compile()
+		var have []string
+		var fe *FieldError
+		if errors.As(haveErr, &fe) {
+			for _, f := range fe.Fields {
+				have = append(have, f.Name)
+			}
+		} else if haveErr != nil {
+			t.Fatalf("TestValidator %v has error %v", i, haveErr)
+		}
+		if fmt.Sprint(have) != fmt.Sprint(tt.want) {
+			t.Fatalf("TestValidator %v has %v but wanted %v", i, have, tt.want)
+		}
+	}
+	_, err := db.Expr(`a = 1 AND Name = 2 OR b = 3`, v).Compile()
+	if want := "doc: invalid fields (a at 1:1, b at 1:23)"; err == nil || err.Error() != want {
+		t.Fatalf("TestValidator has error %v but wanted %v", err, want)
+	}
+}
+
+// TestValidatorMatchesEval checks that the validator accepts
+// exactly the fields evaluation finds on a populated item.
+func TestValidatorMatchesEval(t *testing.T) {
+	item := validatorPerson{
+		validatorBase:  validatorBase{ID: 1},
+		ValidatorAudit: &ValidatorAudit{Created: 2},
+		Name:           "a",
+		Address:        validatorAddress{City: "b"},
+		Tags:           []string{"x"},
+		Scores:         [2]int{1, 2},
+		Meta:           map[string]any{"any": 1},
+		secret:         "s",
+	}
+	fields := []string{"ID", "Created", "ValidatorAudit", "ValidatorAudit.Created", "validatorBase", "Name",
+		"Address.City", "Tags[0]", "Scores[1]", "Meta.any", "secret", "Nope", "Address.Zip", "Name[0]"}
+	db := &DB{format: parser.DefaultFormat()}
+	v := NewStructValidator[validatorPerson]()
+	for i, f := range fields {
+		found, err := EvalBool(db.Expr("EXISTS("+f+")", nil), item, Opt{})
+		if err != nil {
+			t.Fatalf("TestValidatorMatchesEval %v has error %v", i, err)
+		} else if accepted := v.AcceptField(f); accepted != found {
+			t.Fatalf("TestValidatorMatchesEval %v %v accepted is %v but found is %v", i, f, accepted, found)
+		}
+	}
+}
+
+type validatorBase struct {
+	ID int
+}
+
+type ValidatorAudit struct {
+	Created int
+}
+
+type validatorAddress struct {
+	City string
+}
+
+type validatorPerson struct {
+	validatorBase
+	*ValidatorAudit
+	Name    string
+	Address validatorAddress
+	Tags    []string
+	Scores  [2]int
+	Meta    map[string]any
+	secret  string
+}
+
+// ---------------------------------------------------------
+// TEST-EXPR-JSON
+func TestExprJSON(t *testing.T) {
+	table := []struct {
+		dialect string
+		term    string
+		args    []any
+		want    string
+		wantErr error
+	}{
+		{"", `a = 1 AND (b.c[0] = "x" OR d)`, nil, `a = 1 AND (b.c[0] = x OR d)`, nil},
+		{PostgresDialect, `a IN (1, 2) AND b = ?`, []any{"y"}, `"a" IN (1, 2) AND "b" = 'y'`, nil},
+		{PostgresDialect, `at > t"2026-01-02T00:00:00Z" AND ttl < d"1m"`, nil, `"at" > TIMESTAMPTZ '2026-01-02 00:00:00Z' AND "ttl" < INTERVAL '60 seconds'`, nil},
+		{SQLiteDialect, `a = ?`, nil, `"a" = ?1`, nil},
+		{"", `a = `, nil, "", ErrParse},
+		{"", `a = $1 AND b = $3`, []any{1, 2}, "", ErrBadRequest},
+	}
+	for i, v := range table {
+		var f Format = parser.DefaultFormat()
+		if v.dialect != "" {
+			f, _ = Dialect(v.dialect)
+		}
+		db := &DB{format: f}
+		want := db.Expr(v.term, nil, v.args...)
+		var have string
+		data, haveErr := ExprToJSON(want)
+		if haveErr == nil {
+			var e Expr
+			e, haveErr = ExprFromJSON(f, data)
+			if haveErr == nil {
+				have, _ = e.Format()
+				if !Equal(e, want) {
+					t.Fatalf("TestExprJSON %v is not equal to the original", i)
+				}
+			}
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestExprJSON %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestExprJSON %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestExprJSON %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+
+	// The encoding/json hooks keep the format of the receiver.
+	f, _ := Dialect(MySQLDialect)
+	src, _ := (&DB{format: f}).Expr(`a = "x"`, nil).Compile()
+	data, err := json.Marshal(src)
+	if err != nil {
+		t.Fatalf("TestExprJSON has marshal error %v", err)
+	}
+	dst, _ := (&DB{format: f}).Expr(`b = 1`, nil).Compile()
+	if err = json.Unmarshal(data, dst); err != nil {
+		t.Fatalf("TestExprJSON has unmarshal error %v", err)
+	} else if have, _ := dst.Format(); have != "`a` = 'x'" {
+		t.Fatalf("TestExprJSON has \"%v\" after unmarshal", have)
+	}
+	if _, err = ExprFromJSON(nil, []byte(`{"version":2}`)); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("TestExprJSON has error %v for a future version", err)
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-NORMALIZE
+func TestNormalize(t *testing.T) {
+	table := []struct {
+		term    string
+		ts      []Transform
+		want    string
+		wantErr error
+	}{
+		{`NOT (a = 1 OR b != 2)`, nil, `a != 1 AND b == 2`, nil},
+		{`(a = 1 AND true) OR false`, []Transform{FoldConstants, StripGroups}, `a = 1`, nil},
+		{`a = 1 OR a = 1 OR (b = 2 OR c = 3)`, []Transform{Flatten, Dedupe}, `a = 1 OR b = 2 OR c = 3`, nil},
+		{`a = 1 AND (b = 2 OR c = 3)`, []Transform{ToDNF}, `a = 1 AND b = 2 OR a = 1 AND c = 3`, nil},
+		{`a = `, nil, "", ErrParse},
+	}
+	db := &DB{format: parser.DefaultFormat()}
+	for i, v := range table {
+		e, haveErr := Normalize(db.Expr(v.term, nil), v.ts...)
+		var have string
+		if haveErr == nil {
+			have, haveErr = e.Format()
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestNormalize %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestNormalize %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestNormalize %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+
+	// The normalized expression keeps the original's format.
+	f, _ := Dialect(PostgresDialect)
+	e, err := Normalize((&DB{format: f}).Expr(`NOT (a = 1 OR b = 2)`, nil))
+	if have, _ := e.Format(); err != nil || have != `"a" <> 1 AND "b" <> 2` {
+		t.Fatalf("TestNormalize has \"%v\" and error %v", have, err)
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-FINGERPRINT
+func TestFingerprint(t *testing.T) {
+	pg, _ := Dialect(PostgresDialect)
+	db := &DB{format: parser.DefaultFormat()}
+	table := []struct {
+		a, b     Expr
+		abstract bool
+		want     bool
+	}{
+		{db.Expr("a = 1 AND b = 2", nil), db.Expr("(a = 1) and b = 2", nil), false, true},
+		// Formatting is ignored.
+		{db.Expr("a = 1", nil), (&DB{format: pg}).Expr("a = 1", nil), false, true},
+		{db.Expr("a = ?", nil, int64(1)), db.Expr("a = 1", nil), true, true},
+		{db.Build(Field("a").Eq(1).And(Field("b").In("x")), nil), db.Expr(`a == 1 AND b IN ("x")`, nil), false, true},
+		{db.Expr("NOT (a = 1 OR b)", nil), db.Expr("a != 1 AND NOT b", nil), false, true},
+		{db.Expr("id = 5", nil), db.Expr("id = 6", nil), false, false},
+		{db.Expr("id = 5", nil), db.Expr("id = 6", nil), true, true},
+		{db.Expr("id = 5", nil), db.Expr("name = 5", nil), true, false},
+	}
+	for i, v := range table {
+		opts := FingerprintOptions{AbstractValues: v.abstract}
+		fa, errA := Fingerprint(v.a, opts)
+		fb, errB := Fingerprint(v.b, opts)
+		if errA != nil || errB != nil {
+			t.Fatalf("TestFingerprint %v has errors %v %v", i, errA, errB)
+		} else if have := fa == fb; have != v.want {
+			t.Fatalf("TestFingerprint %v has %v but wanted %v", i, have, v.want)
+		} else if !v.abstract && Equal(v.a, v.b) != v.want {
+			t.Fatalf("TestFingerprint %v has Equal %v but wanted %v", i, !v.want, v.want)
+		}
+	}
+
+	bad := db.Expr("a = ", nil)
+	if _, err := Fingerprint(bad, FingerprintOptions{}); !errors.Is(err, ErrParse) {
+		t.Fatalf("TestFingerprint has error %v but exptected %v", err, ErrParse)
+	} else if Equal(bad, bad) {
+		t.Fatalf("TestFingerprint has an invalid expression equal to itself")
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-MONGO-FILTER
+func TestMongoFilter(t *testing.T) {
+	db := &DB{format: parser.DefaultFormat()}
+	table := []struct {
+		e       Expr
+		want    string
+		wantErr error
+	}{
+		{db.Expr("age >= ? AND name IN (a, b)", nil, 21), `{"$and":[{"age":{"$gte":21}},{"name":{"$in":["a","b"]}}]}`, nil},
+		{db.Expr("NOT (a.b[0] = 1)", nil), `{"$nor":[{"a.b.0":{"$eq":1}}]}`, nil},
+		{db.Build(Field("age").Between(1, 2).Or(Field("x").IsNull()), nil), `{"$or":[{"age":{"$gte":1,"$lte":2}},{"x":{"$type":"null"}}]}`, nil},
+		{db.Expr("a = ", nil), "", ErrParse},
+		{db.Expr("a = b + 1", nil), "", ErrSyntax},
+		{db.Expr("lower(name) = a", nil), "", ErrUnsupported},
+		{db.Build(Cond{}, nil), "", ErrBadRequest},
+	}
+	for i, v := range table {
+		filter, haveErr := MongoFilter(v.e)
+		have := ""
+		if haveErr == nil {
+			data, _ := json.Marshal(filter)
+			have = string(data)
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestMongoFilter %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestMongoFilter %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestMongoFilter %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-FORMAT-LAYERS
+func TestFormatLayers(t *testing.T) {
+	table := []struct {
+		dialect string
+		f       Format
+		term    string
+		args    []any
+		want    string
+		wantErr error
+	}{
+		{PostgresDialect, nil, `a = "x" AND b = ?`, []any{"y"}, `"a" = 'x' AND "b" = 'y'`, nil},
+		{PostgresDialect, unicodeFormat{}, `a = "x" AND b = ?`, []any{"y"}, `"a" = U'X' AND "b" = U'Y'`, nil},
+		{PostgresDialect, unicodeFormat{}, `a = 1 AND b = ?`, []any{2}, `"a" = 1 AND "b" = 2`, nil},
+		{PostgresDialect, unicodeFormat{}, `a = ? AND b = $2`, nil, `"a" = $1 AND "b" = $2`, nil},
+		{MySQLDialect, unicodeFormat{}, `a = :name`, []any{Named("name", "y")}, "`a` = U'Y'", nil},
+		{PostgresDialect, bracketFormat{}, `a.b = "x"`, nil, `[a].[b] = U'X'`, nil},
+		{SQLiteDialect, bracketFormat{}, `order = 1`, nil, `[order] = 1`, nil},
+	}
+	for i, v := range table {
+		f, haveErr := FormatWithDialect(v.dialect, v.f)
+		var have string
+		if haveErr == nil {
+			db := &DB{format: f}
+			have, haveErr = db.Expr(v.term, nil, v.args...).Format()
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestFormatLayers %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestFormatLayers %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestFormatLayers %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-FORMAT-WITH-DEFAULTS
+func TestFormatWithDefaults(t *testing.T) {
+	table := []struct {
+		dialect string
+		term    string
+		args    []any
+		want    string
+		wantErr error
+	}{
+		{PostgresDialect, `a = 1 AND b = ?`, []any{"x"}, `"a" = 1 AND "b" = 'x'`, nil},
+		{PostgresDialect, `a.b = 1`, nil, `"a"->>'b' = 1`, nil},
+		// The dialect's refusals aren't papered over by the defaults.
+		{PostgresDialect, `a = ?`, []any{nil}, "", ErrUnsupported},
+		{SQLiteDialect, `ttl < ?`, []any{time.Hour}, "", ErrUnsupported},
+		{MySQLDialect, `a[0] = 1`, nil, "", ErrUnsupported},
+	}
+	for i, v := range table {
+		dialect, _ := Dialect(v.dialect)
+		db := &DB{format: FormatWithDefaults(dialect)}
+		have, haveErr := db.Expr(v.term, nil, v.args...).Format()
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestFormatWithDefaults %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestFormatWithDefaults %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestFormatWithDefaults %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// unicodeFormat renders strings as upper-case unicode literals,
+// and leaves everything else to the format below it.
+type unicodeFormat struct{}
+
+func (unicodeFormat) Keyword(s string) string {
+	return ""
+}
+
+func (unicodeFormat) Value(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return "U'" + strings.ToUpper(s) + "'", nil
+	}
+	return "", fmt.Errorf("unhandled value %T", v)
+}
+
+// bracketFormat quotes identifiers, leaving paths to the layers below it.
+type bracketFormat struct {
+	unicodeFormat
+}
+
+func (bracketFormat) Identifier(name string) (string, error) {
+	return "[" + name + "]", nil
+}
@@ -0,0 +1,39 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// Expose the parser errors so clients can use errors.Is()
+// and errors.As() without importing the parser package.
+var (
+	ErrBadRequest = parser.ErrBadRequest
+	ErrCondition  = parser.ErrCondition
+	ErrEval       = parser.ErrEval
+	ErrSyntax     = parser.ErrSyntax
+	ErrMalformed  = parser.ErrMalformed
+	ErrMismatch   = parser.ErrMismatch
+	ErrParse      = parser.ErrParse
+	ErrUnhandled  = parser.ErrUnhandled
+	// ErrUnsupported is returned when a Format can't render an operator.
+	ErrUnsupported = parser.ErrUnsupported
+
+	// Parsing errors when an expression exceeds the ParseOptions limits.
+	ErrTooLong       = parser.ErrTooLong
+	ErrTooManyTokens = parser.ErrTooManyTokens
+	ErrTooDeep       = parser.ErrTooDeep
+	ErrTooManyItems  = parser.ErrTooManyItems
+	ErrTooManyFields = parser.ErrTooManyFields
+)
+
+// Error is the error produced when parsing and evaluating expressions.
+type Error = parser.Error
+
+// Position describes a location in an expression string.
+type Position = parser.Position
+
+// FormatError answers err as a string, followed by the line of
+// expr that contains the error and a caret under the error position.
+func FormatError(expr string, err error) string {
+	return parser.FormatError(expr, err)
+}
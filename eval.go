@@ -0,0 +1,49 @@
+package doc
+
+import (
+	"cmp"
+
+	"github.com/hackborn/doc/parser"
+)
+
+// Opt contains options for evaluation.
+type Opt = parser.Opt
+
+// Eval evaluates the expression against item, which can be a
+// struct or a map with string keys. See EvalBool() etc. for typed results.
+func Eval(e Expr, item any, opt Opt) (any, error) {
+	ast, err := Ast(e)
+	if err != nil {
+		return nil, err
+	}
+	return parser.Eval(ast, item, opt)
+}
+
+// EvalBool evaluates the expression against item, answering a bool.
+func EvalBool(e Expr, item any, opt Opt) (bool, error) {
+	// A nil AST produces the OnError value.
+	ast, err := Ast(e)
+	v, evalErr := parser.EvalBool(ast, item, opt)
+	return v, cmp.Or(err, evalErr)
+}
+
+// EvalFloat64 evaluates the expression against item, answering a float64.
+func EvalFloat64(e Expr, item any, opt Opt) (float64, error) {
+	ast, err := Ast(e)
+	v, evalErr := parser.EvalFloat64(ast, item, opt)
+	return v, cmp.Or(err, evalErr)
+}
+
+// EvalInt evaluates the expression against item, answering an int.
+func EvalInt(e Expr, item any, opt Opt) (int, error) {
+	ast, err := Ast(e)
+	v, evalErr := parser.EvalInt(ast, item, opt)
+	return v, cmp.Or(err, evalErr)
+}
+
+// EvalString evaluates the expression against item, answering a string.
+func EvalString(e Expr, item any, opt Opt) (string, error) {
+	ast, err := Ast(e)
+	v, evalErr := parser.EvalString(ast, item, opt)
+	return v, cmp.Or(err, evalErr)
+}
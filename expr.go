@@ -40,12 +40,47 @@ func NewExpr(f Format, tokens ...any) (Expr, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &compiledExpr{ast: ast, formatted: sb.String()}, nil
+	return &compiledExpr{ast: ast, f: f, formatted: sb.String()}, nil
+}
+
+// Ast answers the compiled tree of e, for clients that translate
+// the expression directly. Use Visit() and Walk() to inspect it.
+func Ast(e Expr) (AstNode, error) {
+	c, err := compile(e)
+	if err != nil {
+		return nil, err
+	}
+	return c.ast, nil
+}
+
+// ExprToJSON answers the compiled tree of e as a versioned JSON
+// document. See ExprFromJSON().
+func ExprToJSON(e Expr) ([]byte, error) {
+	ast, err := Ast(e)
+	if err != nil {
+		return nil, err
+	}
+	return parser.MarshalAst(ast)
+}
+
+// ExprFromJSON answers a compiled expression from a JSON document
+// created by ExprToJSON(), formatted with f. f can be nil
+// for the default formatting.
+func ExprFromJSON(f Format, data []byte) (Expr, error) {
+	ast, err := parser.UnmarshalAst(data)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		f = parser.DefaultFormat()
+	}
+	return newCompiledExpr(ast, f)
 }
 
 // compiledExpr is a parsed, cached expression.
 type compiledExpr struct {
 	ast parser.AstNode
+	f   Format
 
 	// Formatted is the formatted string produced by the parsed expression.
 	formatted string
@@ -68,18 +103,85 @@ func (e *compiledExpr) Extract(fn any) error {
 	return e.ast.Extract(fn)
 }
 
+// MarshalJSON answers me as a JSON document. See ExprToJSON().
+func (e *compiledExpr) MarshalJSON() ([]byte, error) {
+	return ExprToJSON(e)
+}
+
+// UnmarshalJSON replaces me with the expression in data,
+// keeping my format.
+func (e *compiledExpr) UnmarshalJSON(data []byte) error {
+	f := e.f
+	if f == nil {
+		f = parser.DefaultFormat()
+	}
+	expr, err := ExprFromJSON(f, data)
+	if err != nil {
+		return err
+	}
+	*e = *(expr.(*compiledExpr))
+	return nil
+}
+
+// compile answers e as a compiled expression from this package.
+func compile(e Expr) (*compiledExpr, error) {
+	if e == nil {
+		return nil, fmt.Errorf("No expression")
+	}
+	expr, err := e.Compile()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := expr.(*compiledExpr)
+	if !ok || c.ast == nil {
+		return nil, fmt.Errorf("No AST")
+	}
+	return c, nil
+}
+
+// newCompiledExpr answers a compiled expression for the
+// AST, formatting it and collecting the fields.
+func newCompiledExpr(ast parser.AstNode, f Format) (*compiledExpr, error) {
+	var sb strings.Builder
+	err := ast.Format(parser.FormatArgs{Writer: &sb, Format: f})
+	if err != nil {
+		return nil, err
+	}
+	fa := &parser.FieldArgs{}
+	err = ast.Fields(fa)
+	if err != nil {
+		return nil, err
+	}
+	return &compiledExpr{ast: ast, f: f, formatted: sb.String(), fields: fa.Fields}, nil
+}
+
 // rawExpression contains a raw expression term and the information
 // necessary to compile it.
 type rawExpression struct {
 	term string
+	// ast, when set, is used in place of parsing term, and
+	// err is any error from building it.
+	ast  parser.AstNode
+	err  error
 	v    Validator
 	f    Format
+	args []any
+	opts ParseOptions
 }
 
 func (e *rawExpression) Compile() (Expr, error) {
-	ast, err := parser.Parse(e.term)
+	ast, err := e.ast, e.err
 	if err != nil {
 		return nil, err
+	} else if ast == nil {
+		ast, err = parser.ParseWith(e.term, e.opts)
+		if err != nil {
+			return nil, err
+		}
+		err = parser.Bind(ast, e.args...)
+		if err != nil {
+			return nil, err
+		}
 	}
 	var sb strings.Builder
 	args := parser.FormatArgs{Writer: &sb, Format: e.f}
@@ -92,13 +194,17 @@ func (e *rawExpression) Compile() (Expr, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &compiledExpr{ast: ast, formatted: sb.String(), fields: fa.Fields}, nil
+	err = validateFields(e.v, fa.Refs)
+	if err != nil {
+		return nil, err
+	}
+	return &compiledExpr{ast: ast, f: e.f, formatted: sb.String(), fields: fa.Fields}, nil
 }
 
 func (e *rawExpression) Format() (string, error) {
-	expr, _ := e.Compile()
-	if expr == nil {
-		return "", nil
+	expr, err := e.Compile()
+	if err != nil {
+		return "", err
 	}
 	return expr.Format()
 }
@@ -110,3 +216,8 @@ func (e *rawExpression) Extract(fn any) error {
 	}
 	return expr.Extract(fn)
 }
+
+// MarshalJSON answers me as a JSON document. See ExprToJSON().
+func (e *rawExpression) MarshalJSON() ([]byte, error) {
+	return ExprToJSON(e)
+}
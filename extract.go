@@ -6,3 +6,24 @@ import (
 
 // ExtractBinary is passed to Expr.Extract.
 type ExtractBinary = parser.ExtractBinary
+
+// ExtractComparison is passed to Expr.Extract.
+type ExtractComparison = parser.ExtractComparison
+
+// ExtractIn is passed to Expr.Extract.
+type ExtractIn = parser.ExtractIn
+
+// ExtractMatch is passed to Expr.Extract.
+type ExtractMatch = parser.ExtractMatch
+
+// ExtractPredicate is passed to Expr.Extract.
+type ExtractPredicate = parser.ExtractPredicate
+
+// ExtractBetween is passed to Expr.Extract.
+type ExtractBetween = parser.ExtractBetween
+
+// ExtractNot is passed to Expr.Extract.
+type ExtractNot = parser.ExtractNot
+
+// Range is passed to ExtractBetween.
+type Range = parser.Range
@@ -0,0 +1,41 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// FingerprintOptions controls Fingerprint().
+type FingerprintOptions = parser.FingerprintOptions
+
+// Equal answers true if the normalized trees of both
+// expressions are identical. Formatting is ignored.
+func Equal(a, b Expr) bool {
+	x, err := normalizedAst(a)
+	if err != nil {
+		return false
+	}
+	y, err := normalizedAst(b)
+	if err != nil {
+		return false
+	}
+	return parser.Equal(x, y)
+}
+
+// Fingerprint answers a stable hash of the normalized tree
+// of e, suitable as a cache key.
+func Fingerprint(e Expr, opts FingerprintOptions) (string, error) {
+	ast, err := normalizedAst(e)
+	if err != nil {
+		return "", err
+	}
+	return parser.Fingerprint(ast, opts), nil
+}
+
+// normalizedAst answers the tree of e with the standard normalization.
+func normalizedAst(e Expr) (AstNode, error) {
+	ast, err := Ast(e)
+	if err != nil {
+		return nil, err
+	}
+	return parser.Normalize(ast)
+}
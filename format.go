@@ -1,6 +1,11 @@
 package doc
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/hackborn/doc/parser"
 )
 
@@ -20,6 +25,20 @@ func FormatWithDefaults(f Format) Format {
 	return &compositeFormat{main: f, fallback: fallback}
 }
 
+// FormatWithDialect answers the named SQL dialect, with f layered
+// on top of it: anything f leaves unhandled falls back to the
+// dialect. f can be nil if you just want the dialect.
+func FormatWithDialect(name string, f Format) (Format, error) {
+	dialect, ok := parser.LookupDialect(name)
+	if !ok {
+		return nil, fmt.Errorf("doc: unknown dialect %v (select from %v)", name, Dialects())
+	}
+	if f == nil {
+		return dialect, nil
+	}
+	return &compositeFormat{main: f, fallback: dialect}, nil
+}
+
 // compositeFormat provides two levels of formatting rules.
 type compositeFormat struct {
 	main     Format
@@ -34,10 +53,72 @@ func (f *compositeFormat) Keyword(s string) string {
 	return f.fallback.Keyword(s)
 }
 
+// Value falls back when the main format doesn't handle v,
+// but ErrUnsupported is a deliberate refusal and is kept.
 func (f *compositeFormat) Value(v interface{}) (string, error) {
 	s, err := f.main.Value(v)
-	if err == nil {
+	if err == nil || errors.Is(err, ErrUnsupported) {
 		return s, err
 	}
 	return f.fallback.Value(v)
 }
+
+// Param renders bound values through my own Value, so the main
+// format's values apply to bound and literal values alike. Only
+// the placeholder syntax falls back.
+func (f *compositeFormat) Param(p Param) (string, error) {
+	if pf, ok := f.main.(ParamFormat); ok {
+		return pf.Param(p)
+	}
+	if p.Bound {
+		return f.Value(p.Value)
+	}
+	if pf, ok := f.fallback.(ParamFormat); ok {
+		return pf.Param(p)
+	}
+	return p.Text, nil
+}
+
+// Path falls back when the main format doesn't implement
+// PathFormat, or answers an empty string, which is never a valid
+// field. A main format that only quotes identifiers renders paths
+// in dot notation, so its quoting is never lost.
+func (f *compositeFormat) Path(p FieldPath) (string, error) {
+	if pf, ok := f.main.(PathFormat); ok {
+		if s, err := pf.Path(p); err != nil || s != "" {
+			return s, err
+		}
+	}
+	if _, ok := f.main.(IdentifierFormat); ok {
+		var sb strings.Builder
+		for i, seg := range p {
+			if seg.IsIndex {
+				sb.WriteString("[" + strconv.Itoa(seg.Index) + "]")
+				continue
+			}
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			s, err := f.Identifier(seg.Name)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(s)
+		}
+		return sb.String(), nil
+	}
+	if pf, ok := f.fallback.(PathFormat); ok {
+		return pf.Path(p)
+	}
+	return p.String(), nil
+}
+
+func (f *compositeFormat) Identifier(name string) (string, error) {
+	if idf, ok := f.main.(IdentifierFormat); ok {
+		return idf.Identifier(name)
+	}
+	if idf, ok := f.fallback.(IdentifierFormat); ok {
+		return idf.Identifier(name)
+	}
+	return name, nil
+}
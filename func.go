@@ -0,0 +1,33 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// Func declares a function that can be called in expressions.
+type Func = parser.Func
+
+// ArgType describes the type of a function argument or result.
+type ArgType = parser.ArgType
+
+const (
+	AnyArg      = parser.AnyArg
+	StringArg   = parser.StringArg
+	NumberArg   = parser.NumberArg
+	BoolArg     = parser.BoolArg
+	TimeArg     = parser.TimeArg
+	DurationArg = parser.DurationArg
+	ListArg     = parser.ListArg
+)
+
+// RegisterFunc makes a function available to expressions.
+// Drivers register the functions they support, and map
+// their names with Format.Keyword.
+func RegisterFunc(f Func) {
+	parser.RegisterFunc(f)
+}
+
+// Funcs returns a sorted list of the names of the registered functions.
+func Funcs() []string {
+	return parser.Funcs()
+}
@@ -0,0 +1,17 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// MongoFilter answers e as a MongoDB query filter, i.e.
+// {"$and": [{"age": {"$gte": 21}}, ...]}, for document-store
+// drivers. Constructs with no filter equivalent, such as
+// comparing two fields, are ErrUnsupported.
+func MongoFilter(e Expr) (map[string]any, error) {
+	ast, err := Ast(e)
+	if err != nil {
+		return nil, err
+	}
+	return parser.MongoFilter(ast)
+}
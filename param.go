@@ -0,0 +1,23 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// Param describes a placeholder in an expression. It is
+// supplied to ParamFormat.Param, and to the Extract interfaces
+// in place of a value when the placeholder is unbound.
+type Param = parser.Param
+
+// ParamFormat is an optional extension to Format
+// for rendering placeholders.
+type ParamFormat = parser.ParamFormat
+
+// NamedArg supplies the value for a named placeholder.
+type NamedArg = parser.NamedArg
+
+// Named answers an argument for the named placeholder
+// (i.e. ":name") in an expression.
+func Named(name string, value any) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
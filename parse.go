@@ -0,0 +1,19 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// ParseOptions contains options for parsing expressions,
+// including limits on the size of an expression. Supply it
+// to DB.ExprWith.
+type ParseOptions = parser.ParseOptions
+
+// Default limits for ParseOptions.
+const (
+	DefaultMaxLength    = parser.DefaultMaxLength
+	DefaultMaxTokens    = parser.DefaultMaxTokens
+	DefaultMaxDepth     = parser.DefaultMaxDepth
+	DefaultMaxListItems = parser.DefaultMaxListItems
+	DefaultMaxFields    = parser.DefaultMaxFields
+)
@@ -29,7 +29,17 @@ type FormatArgs struct {
 
 type FieldArgs struct {
 	Fields []string
-	Ctx    FormatContext
+	// Refs parallels Fields, providing the location of each field.
+	Refs []FieldRef
+	Ctx  FormatContext
+}
+
+// FieldRef describes a field referenced in an expression.
+// Name is the Path in dot notation.
+type FieldRef struct {
+	Name string
+	Path FieldPath
+	Pos  Position
 }
 
 // ------------------------------------------------------------
@@ -61,14 +71,14 @@ func (n *binaryNode) Format(args FormatArgs) error {
 	newArgs.Writer = lhW
 	// LHS
 	newArgs.Ctx = n.lhsContext(args.Ctx)
-	err := n.Lhs.Format(newArgs)
+	err := formatChild(n.Lhs, newArgs, n.needsParens(n.Lhs, false))
 	if err != nil {
 		return err
 	}
 	// RHS
 	newArgs.Writer = rhW
 	newArgs.Ctx = n.rhsContext(args.Ctx)
-	err = n.Rhs.Format(newArgs)
+	err = formatChild(n.Rhs, newArgs, n.needsParens(n.Rhs, true))
 	if err != nil {
 		return err
 	}
@@ -92,33 +102,148 @@ func (n *binaryNode) Fields(args *FieldArgs) error {
 	return cmp.Or(err, n.Rhs.Fields(args))
 }
 
-func (n *binaryNode) Extract(_fn any) error {
-	fn, ok := _fn.(ExtractBinary)
-	if !ok {
-		return nil
-	}
+func (n *binaryNode) Extract(fn any) error {
 	if err := n.stateErr(); err != nil {
 		return err
 	}
 
 	switch n.Keyword {
 	case AndKeyword, OrKeyword, ListKeyword:
-		err := fn.BinaryConjunction(n.Keyword)
-		err = cmp.Or(err, n.Lhs.Extract(_fn), n.Rhs.Extract(_fn))
-		if err != nil {
-			return err
+		if eb, ok := fn.(ExtractBinary); ok {
+			err := eb.BinaryConjunction(n.Keyword)
+			if err != nil {
+				return err
+			}
 		}
+		return cmp.Or(n.Lhs.Extract(fn), n.Rhs.Extract(fn))
 	case AssignKeyword:
-		if lhs, rhs, err := n.basicValues(); err == nil {
-			err := fn.BinaryAssignment(lhs, rhs)
+		if eb, ok := fn.(ExtractBinary); ok {
+			lhs, rhs, err := n.basicValues()
+			if err != nil {
+				return err
+			}
+			return eb.BinaryAssignment(lhs, rhs)
+		}
+	case EqualKeyword, NotEqualKeyword, LessKeyword, LessEqualKeyword, GreaterKeyword, GreaterEqualKeyword:
+		if ec, ok := fn.(ExtractComparison); ok {
+			lhs, rhs, err := n.basicValues()
+			if err != nil {
+				return err
+			}
+			return ec.BinaryComparison(lhs, n.Keyword, rhs)
+		}
+	case InKeyword, NotInKeyword:
+		if ei, ok := fn.(ExtractIn); ok {
+			lhs, err := n.lhsField()
+			if err != nil {
+				return err
+			}
+			list, ok := n.Rhs.(*listNode)
+			if !ok {
+				return newMalformedError(n.Keyword + " missing list")
+			}
+			values, err := list.values()
 			if err != nil {
 				return err
 			}
+			return ei.BinaryIn(lhs, n.Keyword, values)
 		}
 	}
 	return nil
 }
 
+func (n *binaryNode) Eval(args EvalArgs) (any, error) {
+	if err := n.stateErr(); err != nil {
+		return nil, err
+	}
+
+	lhsArgs, rhsArgs := args, args
+	lhsArgs.Ctx = n.lhsContext(args.Ctx)
+	rhsArgs.Ctx = n.rhsContext(args.Ctx)
+	switch n.Keyword {
+	case AndKeyword, OrKeyword:
+		lhs, err := evalNode(n.Lhs, lhsArgs)
+		if err != nil {
+			return nil, err
+		}
+		b, err := evalBool(lhs, args.Opt)
+		if err != nil {
+			return nil, err
+		}
+		// Short circuit
+		if b == (n.Keyword == OrKeyword) {
+			return b, nil
+		}
+		rhs, err := evalNode(n.Rhs, rhsArgs)
+		if err != nil {
+			return nil, err
+		}
+		return evalBool(rhs, args.Opt)
+	case ListKeyword:
+		list := &listNode{}
+		if err := list.add(n); err != nil {
+			return nil, err
+		}
+		return list.Eval(args)
+	case AssignKeyword, EqualKeyword, NotEqualKeyword, LessKeyword, LessEqualKeyword, GreaterKeyword, GreaterEqualKeyword:
+		lhs, err := evalNode(n.Lhs, lhsArgs)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := evalNode(n.Rhs, rhsArgs)
+		if err != nil {
+			return nil, err
+		}
+		c, ok, err := evalCompare(lhs, rhs, args.Opt)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// Values that can't be compared are never equal.
+			return n.Keyword == NotEqualKeyword, nil
+		}
+		switch n.Keyword {
+		case NotEqualKeyword:
+			return c != 0, nil
+		case LessKeyword:
+			return c < 0, nil
+		case LessEqualKeyword:
+			return c <= 0, nil
+		case GreaterKeyword:
+			return c > 0, nil
+		case GreaterEqualKeyword:
+			return c >= 0, nil
+		}
+		return c == 0, nil
+	case InKeyword, NotInKeyword:
+		lhs, err := evalNode(n.Lhs, lhsArgs)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := evalNode(n.Rhs, rhsArgs)
+		if err != nil {
+			return nil, err
+		}
+		values, ok := rhs.([]any)
+		if !ok {
+			return nil, newMalformedError(n.Keyword + " missing list")
+		}
+		found := false
+		for _, v := range values {
+			c, ok, err := evalCompare(lhs, v, args.Opt)
+			if err != nil {
+				return nil, err
+			}
+			if ok && c == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (n.Keyword == InKeyword), nil
+	}
+	return nil, newUnhandledError("eval " + n.Keyword)
+}
+
 func (n *binaryNode) stateErr() error {
 	if n.Lhs == nil || n.Rhs == nil {
 		return newMalformedError("binary node")
@@ -129,50 +254,84 @@ func (n *binaryNode) stateErr() error {
 	return nil
 }
 
+// needsParens answers true if child must be parenthesized to
+// preserve its grouping when I am formatted.
+func (n *binaryNode) needsParens(child AstNode, isRhs bool) bool {
+	token := tokenMap[n.Op]
+	if token == nil {
+		return false
+	}
+	if token.BindingPower == comparisonPower && isNot(child) {
+		return true
+	}
+	cp := precedenceOf(child)
+	if cp != token.BindingPower {
+		return cp < token.BindingPower
+	}
+	switch token.Assoc {
+	case leftAssoc:
+		return isRhs
+	case rightAssoc:
+		return !isRhs
+	}
+	return true
+}
+
 func (n *binaryNode) lhsContext(ctx FormatContext) FormatContext {
 	return NoFormatContext
 }
 
 func (n *binaryNode) rhsContext(ctx FormatContext) FormatContext {
 	switch n.Keyword {
-	case AssignKeyword:
+	case AssignKeyword, EqualKeyword, NotEqualKeyword, LessKeyword, LessEqualKeyword, GreaterKeyword, GreaterEqualKeyword,
+		InKeyword, NotInKeyword:
 		return ValueContext
 	default:
 		return NoFormatContext
 	}
 }
 
+// basicValues answers my LHS field and RHS value, or ErrUnsupported
+// if either is something else, i.e. a function call.
 func (n *binaryNode) basicValues() (string, any, error) {
-	lhn, ok1 := n.Lhs.(*valueNode)
-	rhn, ok2 := n.Rhs.(*valueNode)
-	if !ok1 || !ok2 {
-		return "", "", fmt.Errorf("Missing value node")
+	lhs, err := n.lhsField()
+	if err != nil {
+		return "", "", newUnsupportedError(n.Keyword + " on a non-field can't be extracted")
 	}
-	lhs, ok1 := lhn.Value.(string)
-	if !ok1 {
-		return "", "", fmt.Errorf("Missing value node string")
+	rhs, ok := valueOf(n.Rhs)
+	if !ok {
+		return "", "", newUnsupportedError(n.Keyword + " with a non-value can't be extracted")
 	}
-	return lhs, rhn.Value, nil
+	return lhs, rhs, nil
+}
+
+// lhsField answers the field name on my LHS.
+func (n *binaryNode) lhsField() (string, error) {
+	return fieldName(n.Lhs)
 }
 
 // ------------------------------------------------------------
 // VALUE-NODE
 
 // valueNode returns a constant value (string, float, etc.).
+// Pos is the source location, when the node was parsed.
 type valueNode struct {
 	Value interface{}
+	Pos   Position
 }
 
 func (n *valueNode) Format(args FormatArgs) error {
-	s := ""
+	var s string
 	var err error
-	if args.Ctx == ValueContext {
-		s, err = args.Format.Value(n.Value)
-		if err != nil {
-			return err
-		}
+	// Outside a value context strings are fields, but anything
+	// else is still a literal, i.e. the time in t"2020-01-01T00:00:00Z" = a.
+	if name, ok := n.Value.(string); ok && args.Ctx != ValueContext {
+		s, err = formatPath(FieldPath{{Name: name}}, args.Format)
 	} else {
-		s = fmt.Sprintf("%v", n.Value)
+		s, err = args.Format.Value(n.Value)
+	}
+	if err != nil {
+		return err
 	}
 	_, err = args.Writer.WriteString(s)
 	return err
@@ -185,6 +344,7 @@ func (n *valueNode) Fields(args *FieldArgs) error {
 	switch t := n.Value.(type) {
 	case string:
 		args.Fields = append(args.Fields, t)
+		args.Refs = append(args.Refs, FieldRef{Name: t, Path: FieldPath{{Name: t}}, Pos: n.Pos})
 	}
 	return nil
 }
@@ -193,13 +353,105 @@ func (n *valueNode) Extract(any) error {
 	return nil
 }
 
+func (n *valueNode) Eval(args EvalArgs) (any, error) {
+	name, ok := n.Value.(string)
+	if args.Ctx == ValueContext || !ok {
+		return n.Value, nil
+	}
+	v, found := evalField(args.Item, name)
+	if !found && args.Opt.Strict {
+		return nil, newEvalError("no field " + name)
+	}
+	return v, nil
+}
+
+// ------------------------------------------------------------
+// LIST-NODE
+
+// listNode is a parenthesized list of values, i.e. the RHS of IN.
+type listNode struct {
+	Items []AstNode
+}
+
+func (n *listNode) Format(args FormatArgs) error {
+	sep := args.Format.Keyword(ListKeyword)
+	if sep == "" {
+		return newSyntaxError("format returned empty for keyword \"" + ListKeyword + "\"")
+	}
+	args.Writer.WriteString("(")
+	for i, item := range n.Items {
+		if i > 0 {
+			args.Writer.WriteString(sep)
+		}
+		err := item.Format(args)
+		if err != nil {
+			return err
+		}
+	}
+	args.Writer.WriteString(")")
+	return nil
+}
+
+func (n *listNode) Fields(args *FieldArgs) error {
+	for _, item := range n.Items {
+		err := item.Fields(args)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *listNode) Extract(any) error {
+	return nil
+}
+
+func (n *listNode) Eval(args EvalArgs) (any, error) {
+	values := make([]any, 0, len(n.Items))
+	for _, item := range n.Items {
+		v, err := evalNode(item, args)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// add appends the node to my items, flattening list binaries.
+func (n *listNode) add(item AstNode) error {
+	if b, ok := item.(*binaryNode); ok && b.Op == listToken {
+		if err := b.stateErr(); err != nil {
+			return err
+		}
+		return cmp.Or(n.add(b.Lhs), n.add(b.Rhs))
+	}
+	n.Items = append(n.Items, item)
+	return nil
+}
+
+// values answers the raw values of my items.
+func (n *listNode) values() ([]any, error) {
+	values := make([]any, 0, len(n.Items))
+	for _, item := range n.Items {
+		v, ok := valueOf(item)
+		if !ok {
+			return nil, newMalformedError("list item is not a value")
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
 // ------------------------------------------------------------
 // UNARY-NODE
 
 // unaryNode performs a unary operation on the current interface{}.
+// Keyword is only set for operators that are translated by the Format.
 type unaryNode struct {
-	Op    symbol
-	Child AstNode
+	Op      symbol
+	Keyword string
+	Child   AstNode
 }
 
 func (n *unaryNode) Format(args FormatArgs) error {
@@ -210,12 +462,22 @@ func (n *unaryNode) Format(args FormatArgs) error {
 	switch n.Op {
 	case openToken:
 		args.Writer.WriteString("(")
-		n.Child.Format(args)
+		err := n.Child.Format(args)
 		args.Writer.WriteString(")")
+		return err
+	case notToken:
+		keyword := args.Format.Keyword(n.Keyword)
+		if keyword == "" {
+			return newSyntaxError("format returned empty for keyword \"" + n.Keyword + "\"")
+		}
+		args.Writer.WriteString(keyword)
+		return formatChild(n.Child, args, precedenceOf(n.Child) < notPower)
+	case negToken:
+		args.Writer.WriteString("-")
+		return formatChild(n.Child, args, precedenceOf(n.Child) < negPower)
 	default:
 		return newUnhandledError("unary " + strconv.Itoa(int(n.Op)))
 	}
-	return nil
 }
 
 func (n *unaryNode) Fields(args *FieldArgs) error {
@@ -226,10 +488,70 @@ func (n *unaryNode) Fields(args *FieldArgs) error {
 	return n.Child.Fields(args)
 }
 
-func (n *unaryNode) Extract(any) error {
+func (n *unaryNode) Extract(fn any) error {
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+	// Groups are transparent; other operators change the meaning
+	// of the child, so it can't be reported as-is.
+	switch n.Op {
+	case openToken:
+		return n.Child.Extract(fn)
+	case notToken:
+		if en, ok := fn.(ExtractNot); ok {
+			if err := en.UnaryNot(); err != nil {
+				return err
+			}
+			return n.Child.Extract(fn)
+		}
+		if isExtractor(fn) {
+			return newUnsupportedError(NotKeyword + " can't be extracted")
+		}
+	case negToken:
+		if isExtractor(fn) {
+			return newUnsupportedError("negation can't be extracted")
+		}
+	}
 	return nil
 }
 
+func (n *unaryNode) Eval(args EvalArgs) (any, error) {
+	if err := n.stateErr(); err != nil {
+		return nil, err
+	}
+
+	v, err := evalNode(n.Child, args)
+	if err != nil {
+		return nil, err
+	}
+	switch n.Op {
+	case openToken:
+		return v, nil
+	case notToken:
+		b, err := evalBool(v, args.Opt)
+		if err != nil {
+			return nil, err
+		}
+		return !b, nil
+	case negToken:
+		nv, err := evalNormalize(v)
+		if err != nil {
+			return nil, err
+		}
+		switch t := nv.(type) {
+		case int64:
+			return -t, nil
+		case float64:
+			return -t, nil
+		}
+		if args.Opt.Strict {
+			return nil, newMismatchError(fmt.Sprintf("can't negate %T", v))
+		}
+		return nil, nil
+	}
+	return nil, newUnhandledError("unary " + strconv.Itoa(int(n.Op)))
+}
+
 func (n *unaryNode) stateErr() error {
 	if n.Child == nil {
 		return newMalformedError("unary node missing child")
@@ -237,6 +559,85 @@ func (n *unaryNode) stateErr() error {
 	return nil
 }
 
+// ------------------------------------------------------------
+// SUPPORT
+
+// fieldName answers the name of the field referenced by n.
+// Nested fields are answered in dot notation.
+func fieldName(n AstNode) (string, error) {
+	switch t := n.(type) {
+	case *fieldNode:
+		return t.Path.String(), nil
+	case *valueNode:
+		if s, ok := t.Value.(string); ok {
+			return s, nil
+		}
+		return "", fmt.Errorf("Missing value node string")
+	}
+	return "", fmt.Errorf("Missing value node")
+}
+
+// precedenceOf answers the binding power of the operator
+// at the root of n, for deciding where parentheses are required.
+func precedenceOf(n AstNode) int {
+	switch t := n.(type) {
+	case *binaryNode:
+		if token, ok := tokenMap[t.Op]; ok {
+			return token.BindingPower
+		}
+	case *matchNode, *betweenNode:
+		return comparisonPower
+	case *predicateNode:
+		if t.Op != existsToken {
+			return comparisonPower
+		}
+	case *unaryNode:
+		switch t.Op {
+		case notToken:
+			return notPower
+		case negToken:
+			return negPower
+		}
+	}
+	return groupPower
+}
+
+// operandNeedsParens answers true if n must be grouped as the
+// operand of a comparison, match, predicate or BETWEEN. NOT binds
+// tighter than these but is grouped anyway, since in SQL it binds
+// looser and "NOT a = 1" would read as "NOT (a = 1)".
+func operandNeedsParens(n AstNode) bool {
+	return isNot(n) || precedenceOf(n) <= comparisonPower
+}
+
+// isNot answers true if n is a NOT.
+func isNot(n AstNode) bool {
+	u, ok := n.(*unaryNode)
+	return ok && u.Op == notToken
+}
+
+// formatChild formats n, optionally wrapped in parentheses.
+func formatChild(n AstNode, args FormatArgs, parens bool) error {
+	if !parens {
+		return n.Format(args)
+	}
+	args.Writer.WriteString("(")
+	err := n.Format(args)
+	args.Writer.WriteString(")")
+	return err
+}
+
+// valueOf answers the value of a value or param node.
+func valueOf(n AstNode) (any, bool) {
+	switch t := n.(type) {
+	case *valueNode:
+		return t.Value, true
+	case *paramNode:
+		return t.value(), true
+	}
+	return nil, false
+}
+
 // ------------------------------------------------------------
 // CREATION
 
@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// ------------------------------------------------------------
+// RANGE
+
+// Range describes the bounds of a BETWEEN. Bounds are
+// inclusive unless marked exclusive.
+type Range struct {
+	Lower          any
+	Upper          any
+	LowerExclusive bool
+	UpperExclusive bool
+}
+
+// ------------------------------------------------------------
+// BETWEEN-NODE
+
+// betweenNode tests that a field is within a range. The range
+// is inclusive, as in SQL, unless a bound is marked exclusive.
+type betweenNode struct {
+	Keyword        string
+	Field          AstNode
+	Lower          AstNode
+	Upper          AstNode
+	LowerExclusive bool
+	UpperExclusive bool
+}
+
+func (n *betweenNode) Format(args FormatArgs) error {
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+	// Formats only render an inclusive BETWEEN natively; everything
+	// else becomes a pair of comparisons.
+	keyword := args.Format.Keyword(n.Keyword)
+	and := args.Format.Keyword(AndKeyword)
+	if keyword == "" || and == "" || n.LowerExclusive || n.UpperExclusive {
+		return formatChild(n.expand(), args, true)
+	}
+
+	newArgs := args
+	newArgs.Ctx = NoFormatContext
+	err := formatChild(n.Field, newArgs, operandNeedsParens(n.Field))
+	if err != nil {
+		return err
+	}
+	args.Writer.WriteString(keyword)
+	newArgs.Ctx = ValueContext
+	err = formatChild(n.Lower, newArgs, operandNeedsParens(n.Lower))
+	if err != nil {
+		return err
+	}
+	args.Writer.WriteString(and)
+	return formatChild(n.Upper, newArgs, operandNeedsParens(n.Upper))
+}
+
+func (n *betweenNode) Fields(args *FieldArgs) error {
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+
+	prevctx := args.Ctx
+	defer func() { args.Ctx = prevctx }()
+	args.Ctx = NoFormatContext
+	err := n.Field.Fields(args)
+	args.Ctx = ValueContext
+	return cmp.Or(err, n.Lower.Fields(args), n.Upper.Fields(args))
+}
+
+func (n *betweenNode) Extract(fn any) error {
+	eb, ok := fn.(ExtractBetween)
+	if !ok {
+		return nil
+	}
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+	field, err := fieldName(n.Field)
+	if err != nil {
+		return err
+	}
+	lower, lok := valueOf(n.Lower)
+	upper, uok := valueOf(n.Upper)
+	if !lok || !uok {
+		return newMalformedError(n.Keyword + " bound is not a value")
+	}
+	return eb.FieldBetween(field, Range{Lower: lower, Upper: upper, LowerExclusive: n.LowerExclusive, UpperExclusive: n.UpperExclusive})
+}
+
+func (n *betweenNode) Eval(args EvalArgs) (any, error) {
+	if err := n.stateErr(); err != nil {
+		return nil, err
+	}
+
+	fieldArgs, boundArgs := args, args
+	fieldArgs.Ctx, boundArgs.Ctx = NoFormatContext, ValueContext
+	v, err := evalNode(n.Field, fieldArgs)
+	if err != nil {
+		return nil, err
+	}
+	lower, err := evalNode(n.Lower, boundArgs)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := evalNode(n.Upper, boundArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	lc, ok, err := evalCompare(v, lower, args.Opt)
+	if err != nil || !ok {
+		return false, err
+	}
+	uc, ok, err := evalCompare(v, upper, args.Opt)
+	if err != nil || !ok {
+		return false, err
+	}
+	aboveLower := lc > 0 || (lc == 0 && !n.LowerExclusive)
+	belowUpper := uc < 0 || (uc == 0 && !n.UpperExclusive)
+	return aboveLower && belowUpper, nil
+}
+
+// expand answers the equivalent pair of comparisons.
+func (n *betweenNode) expand() AstNode {
+	lower := &binaryNode{Op: gteToken, Keyword: GreaterEqualKeyword, Lhs: n.Field, Rhs: n.Lower}
+	if n.LowerExclusive {
+		lower.Op, lower.Keyword = gtToken, GreaterKeyword
+	}
+	upper := &binaryNode{Op: lteToken, Keyword: LessEqualKeyword, Lhs: n.Field, Rhs: n.Upper}
+	if n.UpperExclusive {
+		upper.Op, upper.Keyword = ltToken, LessKeyword
+	}
+	return &binaryNode{Op: andToken, Keyword: AndKeyword, Lhs: lower, Rhs: upper}
+}
+
+func (n *betweenNode) stateErr() error {
+	if n.Field == nil || n.Lower == nil || n.Upper == nil {
+		return newMalformedError("between node")
+	}
+	if n.Keyword == "" {
+		return newMalformedError("between node missing keyword")
+	}
+	return nil
+}
+
+// newBetweenNode answers a between node from the parse tree, which is
+// either field, lower, upper for the inclusive form, or field, open,
+// lower, upper, close for the interval form.
+func newBetweenNode(n *nodeT) (*betweenNode, error) {
+	children := n.Children
+	if len(children) != 3 && len(children) != 5 {
+		return nil, newParseError(fmt.Sprintf("between has wrong number of children: %v", len(children)))
+	}
+	b := &betweenNode{Keyword: BetweenKeyword}
+	if len(children) == 5 {
+		b.LowerExclusive = children[1].Token.Symbol == openToken
+		b.UpperExclusive = children[4].Token.Symbol == closeToken
+		children = []*nodeT{children[0], children[2], children[3]}
+	}
+	var err error
+	if b.Field, err = children[0].asAst(); err != nil {
+		return nil, err
+	}
+	if _, err = fieldName(b.Field); err != nil {
+		return nil, newSyntaxError(BetweenKeyword + " requires a field")
+	}
+	if b.Lower, err = children[1].asAst(); err != nil {
+		return nil, err
+	}
+	if b.Upper, err = children[2].asAst(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------------
+// BUILD
+
+// The New functions construct the same nodes as the parser,
+// for clients that build expressions in code. Operands are
+// wrapped in groups wherever formatting would parenthesize
+// them, so a built tree is identical to the parsed tree of
+// its formatted text.
+
+// NewValue answers a literal. Numbers are normalized
+// to int64 and float64.
+func NewValue(v any) (AstNode, error) {
+	v, err := evalNormalize(v)
+	if err != nil {
+		return nil, err
+	}
+	switch v.(type) {
+	case nil, bool, int64, float64, string, time.Time, time.Duration:
+		return &valueNode{Value: v}, nil
+	}
+	return nil, newBadRequestError(fmt.Sprintf("unsupported value %T", v))
+}
+
+// NewField answers a reference to the field at path, in dot
+// notation. A plain name is a string value, as when parsed.
+func NewField(path string) (AstNode, error) {
+	if !strings.ContainsAny(path, ".[") {
+		if path == "" {
+			return nil, newSyntaxError("empty field")
+		}
+		return &valueNode{Value: path}, nil
+	}
+	p, err := ParseFieldPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fieldNode{Path: p}, nil
+}
+
+// NewBinary answers AND, OR, a comparison, IN or NOT IN, identified
+// by keyword. The RHS of IN and NOT IN must be a list.
+func NewBinary(keyword string, lhs, rhs AstNode) (AstNode, error) {
+	token, ok := keywordOps[keyword]
+	if !ok {
+		return nil, newSyntaxError("not a binary operator: " + keyword)
+	}
+	switch token.Symbol {
+	case likeToken, startsWithToken, containsToken, matchesToken, isNullToken, isNotNullToken, existsToken:
+		return nil, newSyntaxError("not a binary operator: " + keyword)
+	case inToken, notInToken:
+		list, ok := rhs.(*listNode)
+		if !ok {
+			return nil, newSyntaxError(keyword + " requires a list")
+		} else if len(list.Items) < 1 {
+			return nil, newSyntaxError(keyword + " requires at least one value")
+		}
+	}
+	n := &binaryNode{Op: token.Symbol, Keyword: token.Text}
+	n.Lhs = groupIf(lhs, n.needsParens(lhs, false))
+	n.Rhs = groupIf(rhs, n.needsParens(rhs, true))
+	return n, nil
+}
+
+// NewNot answers the negation of child.
+func NewNot(child AstNode) AstNode {
+	return &unaryNode{Op: notToken, Keyword: NotKeyword, Child: groupIf(child, precedenceOf(child) < notPower)}
+}
+
+// NewNeg answers the arithmetic negation of child. Negated
+// numbers are folded into the value, as when parsed.
+func NewNeg(child AstNode) AstNode {
+	if v, ok := child.(*valueNode); ok {
+		switch t := v.Value.(type) {
+		case int64:
+			return &valueNode{Value: -t}
+		case float64:
+			return &valueNode{Value: -t}
+		}
+	}
+	return &unaryNode{Op: negToken, Child: groupIf(child, precedenceOf(child) < negPower)}
+}
+
+// NewGroup answers child in parentheses.
+func NewGroup(child AstNode) AstNode {
+	return &unaryNode{Op: openToken, Child: child}
+}
+
+// NewList answers a list of items, i.e. the RHS of IN.
+func NewList(items ...AstNode) AstNode {
+	return &listNode{Items: items}
+}
+
+// NewMatch answers LIKE, STARTSWITH, CONTAINS or MATCHES,
+// identified by keyword.
+func NewMatch(keyword string, lhs, pattern AstNode) (AstNode, error) {
+	token, ok := keywordOps[keyword]
+	if !ok {
+		return nil, newSyntaxError("not a match operator: " + keyword)
+	}
+	switch token.Symbol {
+	case likeToken, startsWithToken, containsToken, matchesToken:
+		return newMatchNode(token.Symbol, token.Text, groupIf(lhs, operandNeedsParens(lhs)), pattern)
+	}
+	return nil, newSyntaxError("not a match operator: " + keyword)
+}
+
+// NewPredicate answers IS NULL, IS NOT NULL or EXISTS,
+// identified by keyword.
+func NewPredicate(keyword string, field AstNode) (AstNode, error) {
+	token, ok := keywordOps[keyword]
+	if !ok {
+		return nil, newSyntaxError("not a predicate: " + keyword)
+	}
+	switch token.Symbol {
+	case isNullToken, isNotNullToken:
+		return newPredicateNode(token.Symbol, token.Text, groupIf(field, operandNeedsParens(field)))
+	case existsToken:
+		return newPredicateNode(token.Symbol, token.Text, field)
+	}
+	return nil, newSyntaxError("not a predicate: " + keyword)
+}
+
+// NewBetween answers a range test on field. Bounds are
+// inclusive unless marked exclusive.
+func NewBetween(field, lower, upper AstNode, lowerExclusive, upperExclusive bool) (AstNode, error) {
+	if _, err := fieldName(field); err != nil {
+		return nil, newSyntaxError(BetweenKeyword + " requires a field")
+	}
+	return &betweenNode{
+		Keyword:        BetweenKeyword,
+		Field:          field,
+		Lower:          groupIf(lower, operandNeedsParens(lower)),
+		Upper:          groupIf(upper, operandNeedsParens(upper)),
+		LowerExclusive: lowerExclusive,
+		UpperExclusive: upperExclusive,
+	}, nil
+}
+
+// NewCall answers a call to the registered function name.
+func NewCall(name string, args ...AstNode) (AstNode, error) {
+	grouped := make([]AstNode, len(args))
+	for i, arg := range args {
+		grouped[i] = groupIf(arg, precedenceOf(arg) <= listPower)
+	}
+	return newCallNode(name, grouped, Position{})
+}
+
+// groupIf answers n, in parentheses if group is true.
+func groupIf(n AstNode, group bool) AstNode {
+	if !group {
+		return n
+	}
+	return NewGroup(n)
+}
+
+// keywordOps maps the keywords of binary operators, matches
+// and predicates to their tokens.
+var keywordOps = func() map[string]*tokenT {
+	ops := make(map[string]*tokenT)
+	for _, sym := range []symbol{
+		andToken, orToken, listToken, assignToken,
+		eqlToken, neqToken, ltToken, lteToken, gtToken, gteToken,
+		inToken, notInToken,
+		likeToken, startsWithToken, containsToken, matchesToken,
+		isNullToken, isNotNullToken, existsToken,
+	} {
+		ops[tokenMap[sym].Text] = tokenMap[sym]
+	}
+	return ops
+}()
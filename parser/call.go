@@ -0,0 +1,325 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ------------------------------------------------------------
+// ARG-TYPE
+
+// ArgType describes the type of a function argument or result.
+type ArgType int
+
+const (
+	AnyArg      ArgType = iota // Anything, including nil
+	StringArg                  // string
+	NumberArg                  // int64 or float64
+	BoolArg                    // bool
+	TimeArg                    // time.Time
+	DurationArg                // time.Duration
+	ListArg                    // slice or array
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case AnyArg:
+		return "any"
+	case StringArg:
+		return "string"
+	case NumberArg:
+		return "number"
+	case BoolArg:
+		return "bool"
+	case TimeArg:
+		return "time"
+	case DurationArg:
+		return "duration"
+	case ListArg:
+		return "list"
+	}
+	return "ArgType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// accepts answers true if v, which must be normalized, is of my type.
+func (t ArgType) accepts(v any) bool {
+	switch t {
+	case AnyArg:
+		return true
+	case StringArg:
+		_, ok := v.(string)
+		return ok
+	case NumberArg:
+		switch v.(type) {
+		case int64, float64:
+			return true
+		}
+	case BoolArg:
+		_, ok := v.(bool)
+		return ok
+	case TimeArg:
+		_, ok := v.(time.Time)
+		return ok
+	case DurationArg:
+		_, ok := v.(time.Duration)
+		return ok
+	case ListArg:
+		if v != nil {
+			kind := reflect.ValueOf(v).Kind()
+			return kind == reflect.Slice || kind == reflect.Array
+		}
+	}
+	return false
+}
+
+// ------------------------------------------------------------
+// FUNC
+
+// Func declares a function that can be called in expressions.
+// Calls are checked against the declaration when parsing.
+type Func struct {
+	// Name is the name used in expressions. Names are not case sensitive.
+	Name string
+
+	// Args are the declared argument types. If Variadic is
+	// true, the last type can repeat zero or more times.
+	Args     []ArgType
+	Variadic bool
+
+	// Result is the type answered by the function.
+	Result ArgType
+
+	// Eval performs the function for in-memory evaluation. The
+	// arguments have been checked against Args. It can be nil for
+	// functions that are only implemented by drivers.
+	Eval func(args []any) (any, error)
+}
+
+// checkArity answers an error if n arguments can't be passed to f.
+func (f Func) checkArity(n int) error {
+	want := len(f.Args)
+	if n == want || (f.Variadic && n >= want-1) {
+		return nil
+	}
+	if f.Variadic {
+		return newSyntaxError(fmt.Sprintf("%v requires at least %v arguments, have %v", f.Name, want-1, n))
+	}
+	return newSyntaxError(fmt.Sprintf("%v requires %v arguments, have %v", f.Name, want, n))
+}
+
+// argType answers the declared type of argument i.
+func (f Func) argType(i int) ArgType {
+	if i >= len(f.Args) {
+		return f.Args[len(f.Args)-1]
+	}
+	return f.Args[i]
+}
+
+var (
+	funcsMu sync.RWMutex
+	funcs   = make(map[string]Func)
+)
+
+// RegisterFunc makes a function available to expressions.
+// Drivers can register the functions they support. It panics
+// if the name is empty or already registered.
+func RegisterFunc(f Func) {
+	funcsMu.Lock()
+	defer funcsMu.Unlock()
+	if f.Name == "" {
+		panic("doc: RegisterFunc name is empty")
+	}
+	if f.Variadic && len(f.Args) < 1 {
+		panic("doc: RegisterFunc variadic " + f.Name + " has no args")
+	}
+	name := strings.ToLower(f.Name)
+	if _, dup := funcs[name]; dup {
+		panic("doc: RegisterFunc called twice for " + f.Name)
+	}
+	f.Name = name
+	funcs[name] = f
+}
+
+// LookupFunc answers the registered function with the given name.
+func LookupFunc(name string) (Func, bool) {
+	funcsMu.RLock()
+	defer funcsMu.RUnlock()
+	f, ok := funcs[strings.ToLower(name)]
+	return f, ok
+}
+
+// Funcs returns a sorted list of the names of the registered functions.
+func Funcs() []string {
+	funcsMu.RLock()
+	defer funcsMu.RUnlock()
+	list := make([]string, 0, len(funcs))
+	for name := range funcs {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func init() {
+	RegisterFunc(Func{Name: "lower", Args: []ArgType{StringArg}, Result: StringArg, Eval: func(args []any) (any, error) {
+		return strings.ToLower(args[0].(string)), nil
+	}})
+	RegisterFunc(Func{Name: "upper", Args: []ArgType{StringArg}, Result: StringArg, Eval: func(args []any) (any, error) {
+		return strings.ToUpper(args[0].(string)), nil
+	}})
+	RegisterFunc(Func{Name: "len", Args: []ArgType{AnyArg}, Result: NumberArg, Eval: func(args []any) (any, error) {
+		if s, ok := args[0].(string); ok {
+			return int64(utf8.RuneCountInString(s)), nil
+		}
+		rv := reflect.ValueOf(args[0])
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return int64(rv.Len()), nil
+		}
+		return nil, newMismatchError(fmt.Sprintf("len requires a string, list or map, have %T", args[0]))
+	}})
+	RegisterFunc(Func{Name: "abs", Args: []ArgType{NumberArg}, Result: NumberArg, Eval: func(args []any) (any, error) {
+		if i, ok := args[0].(int64); ok {
+			if i < 0 {
+				return -i, nil
+			}
+			return i, nil
+		}
+		return math.Abs(args[0].(float64)), nil
+	}})
+	RegisterFunc(Func{Name: "now", Result: TimeArg, Eval: func(args []any) (any, error) {
+		return time.Now(), nil
+	}})
+}
+
+// ------------------------------------------------------------
+// CALL-NODE
+
+// callNode calls a registered function. Arguments are
+// interpreted in the context of the call, so unquoted
+// identifiers are fields on the LHS and strings on the RHS.
+type callNode struct {
+	Name string
+	Args []AstNode
+	Pos  Position
+
+	fn Func
+}
+
+// newCallNode answers a call to the named function, checking
+// the arguments against the registered declaration.
+func newCallNode(name string, args []AstNode, pos Position) (*callNode, error) {
+	fn, ok := LookupFunc(name)
+	if !ok {
+		return nil, newSyntaxError("unknown function " + name)
+	}
+	if err := fn.checkArity(len(args)); err != nil {
+		return nil, err
+	}
+	for i, arg := range args {
+		want := fn.argType(i)
+		if want == AnyArg {
+			continue
+		}
+		switch t := arg.(type) {
+		case *valueNode:
+			// Strings might be fields, so they can't be checked until evaluation.
+			if _, isString := t.Value.(string); !isString && !want.accepts(t.Value) {
+				return nil, errorAtPos(newMismatchError(fmt.Sprintf("%v argument %v must be %v, have %T", fn.Name, i+1, want, t.Value)), t.Pos, "")
+			}
+		case *callNode:
+			if t.fn.Result != AnyArg && t.fn.Result != want {
+				return nil, errorAtPos(newMismatchError(fmt.Sprintf("%v argument %v must be %v, have %v", fn.Name, i+1, want, t.fn.Result)), t.Pos, t.Name)
+			}
+		}
+	}
+	return &callNode{Name: fn.Name, Args: args, Pos: pos, fn: fn}, nil
+}
+
+func (n *callNode) Format(args FormatArgs) error {
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+	// Formats can rename functions; anything unknown is written as-is.
+	name := args.Format.Keyword(n.Name)
+	if name == "" {
+		name = n.Name
+	}
+	sep := args.Format.Keyword(ListKeyword)
+	if sep == "" {
+		return newSyntaxError("format returned empty for keyword \"" + ListKeyword + "\"")
+	}
+	args.Writer.WriteString(name)
+	args.Writer.WriteString("(")
+	for i, arg := range n.Args {
+		if i > 0 {
+			args.Writer.WriteString(sep)
+		}
+		if err := formatChild(arg, args, precedenceOf(arg) <= listPower); err != nil {
+			return err
+		}
+	}
+	_, err := args.Writer.WriteString(")")
+	return err
+}
+
+func (n *callNode) Fields(args *FieldArgs) error {
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+	for _, arg := range n.Args {
+		if err := arg.Fields(args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *callNode) Extract(any) error {
+	return nil
+}
+
+func (n *callNode) Eval(args EvalArgs) (any, error) {
+	if err := n.stateErr(); err != nil {
+		return nil, err
+	}
+	if n.fn.Eval == nil {
+		return nil, newUnsupportedError("function " + n.Name + " can't be evaluated")
+	}
+	values := make([]any, 0, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := evalNode(arg, args)
+		if err != nil {
+			return nil, err
+		}
+		v, err = evalNormalize(v)
+		if err != nil {
+			return nil, err
+		}
+		if want := n.fn.argType(i); !want.accepts(v) {
+			if args.Opt.Strict {
+				return nil, newMismatchError(fmt.Sprintf("%v argument %v must be %v, have %T", n.Name, i+1, want, v))
+			}
+			return nil, nil
+		}
+		values = append(values, v)
+	}
+	return n.fn.Eval(values)
+}
+
+func (n *callNode) stateErr() error {
+	if n.Name == "" {
+		return newMalformedError("call node missing name")
+	}
+	if err := n.fn.checkArity(len(n.Args)); err != nil {
+		return newMalformedError(err.Error())
+	}
+	return nil
+}
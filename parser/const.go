@@ -1,10 +1,34 @@
 package parser
 
 const (
-	AndKeyword    = "AND"
-	AssignKeyword = "="
-	ListKeyword   = ","
-	OrKeyword     = "OR"
+	AndKeyword      = "AND"
+	AssignKeyword   = "="
+	BetweenKeyword  = "BETWEEN"
+	ContainsKeyword = "CONTAINS"
+	EqualKeyword    = "=="
+	// EscapeKeyword follows a LIKE pattern, naming its escape
+	// character. Formats that leave it empty write nothing.
+	EscapeKeyword       = "ESCAPE"
+	ExistsKeyword       = "EXISTS"
+	FalseKeyword        = "FALSE"
+	GreaterKeyword      = ">"
+	GreaterEqualKeyword = ">="
+	InKeyword           = "IN"
+	IsKeyword           = "IS"
+	IsNotNullKeyword    = "IS NOT NULL"
+	IsNullKeyword       = "IS NULL"
+	LessKeyword         = "<"
+	LessEqualKeyword    = "<="
+	LikeKeyword         = "LIKE"
+	ListKeyword         = ","
+	MatchesKeyword      = "MATCHES"
+	NotEqualKeyword     = "!="
+	NotInKeyword        = "NOT IN"
+	NotKeyword          = "NOT"
+	NullKeyword         = "NULL"
+	OrKeyword           = "OR"
+	StartsWithKeyword   = "STARTSWITH"
+	TrueKeyword         = "TRUE"
 )
 
 type symbol int
@@ -14,9 +38,16 @@ const (
 	illegalToken symbol = iota
 
 	// Raw values
-	intToken    // 12345
-	floatToken  // 123.45
-	stringToken // "abc"
+	intToken      // 12345
+	floatToken    // 123.45
+	stringToken   // "abc"
+	paramToken    // ?, $1, :name
+	nullToken     // NULL
+	trueToken     // TRUE
+	falseToken    // FALSE
+	timeToken     // t"2026-01-02T03:04:05Z"
+	durationToken // d"1h30m"
+	callToken     // lower(name)
 
 	// Assignment
 	assignToken // =
@@ -32,6 +63,28 @@ const (
 
 	eqlToken // ==
 	neqToken // !=
+	ltToken  // <
+	lteToken // <=
+	gtToken  // >
+	gteToken // >=
+
+	// Membership. The RHS is a parenthesized list.
+	inToken    // IN
+	notInToken // NOT IN
+
+	// Pattern matching. The RHS is a string pattern.
+	likeToken       // LIKE
+	startsWithToken // STARTSWITH
+	containsToken   // CONTAINS
+	matchesToken    // MATCHES (regular expression)
+
+	// Null tests. There is no RHS.
+	isToken        // IS
+	isNullToken    // IS NULL
+	isNotNullToken // IS NOT NULL
+
+	// Range. The RHS is two bounds.
+	betweenToken // BETWEEN
 
 	endComparison
 
@@ -47,14 +100,47 @@ const (
 	// -- UNARIES. All unary operators must be after this
 	startUnary
 
+	notToken    // NOT, not
+	existsToken // EXISTS(field)
+
 	// Enclosures
-	openToken  // (
-	closeToken // ) // All closes must be after the opens
+	openToken         // (
+	openBracketToken  // [
+	closeToken        // ) // All closes must be after the opens
+	closeBracketToken // ]
 
 	// -- END UNARIES.
 	endUnary
 )
 
+// Binding powers, from loosest to tightest: lists, OR, AND,
+// comparisons and then NOT. NOT applies to the operand that
+// follows it, so "NOT a = b" is "(NOT a) = b"; group a comparison
+// to negate it, i.e. "NOT (a = b)". Formatting adds those parens.
+// Values bind at valuePower so that a value following a complete
+// expression reaches its led.
+const (
+	valuePower      = 10
+	listPower       = 20
+	orPower         = 30
+	andPower        = 40
+	comparisonPower = 70
+	notPower        = 80
+	negPower        = 90
+	// groupPower is used when formatting nodes that never
+	// need parentheses, i.e. values and groups.
+	groupPower = 100
+)
+
+// assoc describes how operators of equal binding power group.
+type assoc int
+
+const (
+	leftAssoc  assoc = iota // a OR b OR c is (a OR b) OR c
+	rightAssoc              // - -a is -(-a)
+	nonAssoc                // a = b = c is an error
+)
+
 type FormatContext int
 
 const (
@@ -64,38 +150,108 @@ const (
 
 var (
 	tokenMap = map[symbol]*tokenT{
-		illegalToken: &tokenT{illegalToken, "", 0, emptyNud, emptyLed},
-		intToken:     &tokenT{intToken, "", 10, emptyNud, emptyLed},
-		floatToken:   &tokenT{floatToken, "", 10, emptyNud, emptyLed},
-		stringToken:  &tokenT{stringToken, "", 10, emptyNud, emptyLed},
-		assignToken:  &tokenT{assignToken, AssignKeyword, 80, emptyNud, binaryLed},
-		negToken:     &tokenT{negToken, "", 10, emptyNud, emptyLed},
-		eqlToken:     &tokenT{eqlToken, "==", 70, emptyNud, binaryLed},
-		neqToken:     &tokenT{neqToken, "!=", 70, emptyNud, binaryLed},
-		listToken:    &tokenT{listToken, ListKeyword, 60, emptyNud, binaryLed},
-		andToken:     &tokenT{andToken, AndKeyword, 60, emptyNud, binaryLed},
-		orToken:      &tokenT{orToken, OrKeyword, 60, emptyNud, binaryLed},
-		openToken:    &tokenT{openToken, "(", 0, enclosedNud, emptyLed},
-		closeToken:   &tokenT{closeToken, ")", 0, emptyNud, emptyLed},
+		illegalToken:    &tokenT{illegalToken, "", 0, leftAssoc, emptyNud, emptyLed},
+		intToken:        &tokenT{intToken, "", valuePower, leftAssoc, emptyNud, valueLed},
+		floatToken:      &tokenT{floatToken, "", valuePower, leftAssoc, emptyNud, valueLed},
+		stringToken:     &tokenT{stringToken, "", valuePower, leftAssoc, emptyNud, valueLed},
+		paramToken:      &tokenT{paramToken, "", valuePower, leftAssoc, emptyNud, valueLed},
+		nullToken:       &tokenT{nullToken, NullKeyword, valuePower, leftAssoc, emptyNud, valueLed},
+		trueToken:       &tokenT{trueToken, TrueKeyword, valuePower, leftAssoc, emptyNud, valueLed},
+		falseToken:      &tokenT{falseToken, FalseKeyword, valuePower, leftAssoc, emptyNud, valueLed},
+		timeToken:       &tokenT{timeToken, "", valuePower, leftAssoc, emptyNud, valueLed},
+		durationToken:   &tokenT{durationToken, "", valuePower, leftAssoc, emptyNud, valueLed},
+		callToken:       &tokenT{callToken, "", valuePower, leftAssoc, emptyNud, valueLed},
+		assignToken:     &tokenT{assignToken, AssignKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		negToken:        &tokenT{negToken, "-", valuePower, rightAssoc, prefixNud(negPower), unexpectedLed},
+		eqlToken:        &tokenT{eqlToken, EqualKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		neqToken:        &tokenT{neqToken, NotEqualKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		ltToken:         &tokenT{ltToken, LessKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		lteToken:        &tokenT{lteToken, LessEqualKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		gtToken:         &tokenT{gtToken, GreaterKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		gteToken:        &tokenT{gteToken, GreaterEqualKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		inToken:         &tokenT{inToken, InKeyword, comparisonPower, nonAssoc, emptyNud, inLed},
+		notInToken:      &tokenT{notInToken, NotInKeyword, comparisonPower, nonAssoc, emptyNud, inLed},
+		likeToken:       &tokenT{likeToken, LikeKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		startsWithToken: &tokenT{startsWithToken, StartsWithKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		containsToken:   &tokenT{containsToken, ContainsKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		matchesToken:    &tokenT{matchesToken, MatchesKeyword, comparisonPower, nonAssoc, emptyNud, binaryLed},
+		isToken:         &tokenT{isToken, IsKeyword, comparisonPower, nonAssoc, emptyNud, emptyLed},
+		isNullToken:     &tokenT{isNullToken, IsNullKeyword, comparisonPower, nonAssoc, emptyNud, emptyLed},
+		isNotNullToken:  &tokenT{isNotNullToken, IsNotNullKeyword, comparisonPower, nonAssoc, emptyNud, emptyLed},
+		betweenToken:    &tokenT{betweenToken, BetweenKeyword, comparisonPower, nonAssoc, emptyNud, betweenLed},
+		listToken:       &tokenT{listToken, ListKeyword, listPower, leftAssoc, emptyNud, binaryLed},
+		andToken:        &tokenT{andToken, AndKeyword, andPower, leftAssoc, emptyNud, binaryLed},
+		orToken:         &tokenT{orToken, OrKeyword, orPower, leftAssoc, emptyNud, binaryLed},
+		// NOT binds at comparison power when infix (NOT IN).
+		notToken:    &tokenT{notToken, NotKeyword, comparisonPower, nonAssoc, prefixNud(notPower), emptyLed},
+		existsToken: &tokenT{existsToken, ExistsKeyword, 0, leftAssoc, existsNud, emptyLed},
+		openToken:   &tokenT{openToken, "(", 0, leftAssoc, enclosedNud, emptyLed},
+		closeToken:  &tokenT{closeToken, ")", 0, leftAssoc, emptyNud, emptyLed},
+		// Brackets only appear in BETWEEN intervals.
+		openBracketToken:  &tokenT{openBracketToken, "[", 0, leftAssoc, emptyNud, emptyLed},
+		closeBracketToken: &tokenT{closeBracketToken, "]", 0, leftAssoc, emptyNud, emptyLed},
 	}
 	keywordMap = map[string]*tokenT{
-		AssignKeyword: tokenMap[assignToken],
-		`-`:           tokenMap[negToken],
-		`==`:          tokenMap[eqlToken],
-		`!=`:          tokenMap[neqToken],
-		ListKeyword:   tokenMap[listToken],
-		AndKeyword:    tokenMap[andToken],
-		OrKeyword:     tokenMap[orToken],
-		`(`:           tokenMap[openToken],
-		`)`:           tokenMap[closeToken],
+		AssignKeyword:       tokenMap[assignToken],
+		`-`:                 tokenMap[negToken],
+		EqualKeyword:        tokenMap[eqlToken],
+		NotEqualKeyword:     tokenMap[neqToken],
+		NotKeyword:          tokenMap[notToken],
+		LessKeyword:         tokenMap[ltToken],
+		LessEqualKeyword:    tokenMap[lteToken],
+		GreaterKeyword:      tokenMap[gtToken],
+		GreaterEqualKeyword: tokenMap[gteToken],
+		InKeyword:           tokenMap[inToken],
+		IsKeyword:           tokenMap[isToken],
+		BetweenKeyword:      tokenMap[betweenToken],
+		NullKeyword:         tokenMap[nullToken],
+		TrueKeyword:         tokenMap[trueToken],
+		FalseKeyword:        tokenMap[falseToken],
+		ExistsKeyword:       tokenMap[existsToken],
+		LikeKeyword:         tokenMap[likeToken],
+		StartsWithKeyword:   tokenMap[startsWithToken],
+		ContainsKeyword:     tokenMap[containsToken],
+		MatchesKeyword:      tokenMap[matchesToken],
+		ListKeyword:         tokenMap[listToken],
+		AndKeyword:          tokenMap[andToken],
+		OrKeyword:           tokenMap[orToken],
+		`(`:                 tokenMap[openToken],
+		`)`:                 tokenMap[closeToken],
+		`[`:                 tokenMap[openBracketToken],
+		`]`:                 tokenMap[closeBracketToken],
 	}
 )
 
+func init() {
+	// Assigned here to avoid an initialization cycle,
+	// since these create new tokens while parsing.
+	tokenMap[notToken].led = notLed
+	tokenMap[isToken].led = isLed
+	tokenMap[stringToken].nud = identNud
+}
+
 var (
 	_defaultFormat = &_format{keywords: map[string]string{
-		AndKeyword:    ` ` + AndKeyword + ` `,
-		AssignKeyword: ` ` + AssignKeyword + ` `,
-		ListKeyword:   ListKeyword + ` `,
-		OrKeyword:     ` ` + OrKeyword + ` `,
+		AndKeyword:          ` ` + AndKeyword + ` `,
+		AssignKeyword:       ` ` + AssignKeyword + ` `,
+		BetweenKeyword:      ` ` + BetweenKeyword + ` `,
+		ContainsKeyword:     ` ` + ContainsKeyword + ` `,
+		EqualKeyword:        ` ` + EqualKeyword + ` `,
+		ExistsKeyword:       ExistsKeyword,
+		GreaterKeyword:      ` ` + GreaterKeyword + ` `,
+		GreaterEqualKeyword: ` ` + GreaterEqualKeyword + ` `,
+		InKeyword:           ` ` + InKeyword + ` `,
+		IsNotNullKeyword:    ` ` + IsNotNullKeyword,
+		IsNullKeyword:       ` ` + IsNullKeyword,
+		LessKeyword:         ` ` + LessKeyword + ` `,
+		LessEqualKeyword:    ` ` + LessEqualKeyword + ` `,
+		LikeKeyword:         ` ` + LikeKeyword + ` `,
+		ListKeyword:         ListKeyword + ` `,
+		MatchesKeyword:      ` ` + MatchesKeyword + ` `,
+		NotEqualKeyword:     ` ` + NotEqualKeyword + ` `,
+		NotInKeyword:        ` ` + NotInKeyword + ` `,
+		NotKeyword:          NotKeyword + ` `,
+		OrKeyword:           ` ` + OrKeyword + ` `,
+		StartsWithKeyword:   ` ` + StartsWithKeyword + ` `,
 	}}
 )
@@ -0,0 +1,286 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------------
+// DIALECT-REGISTRY
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = make(map[string]Format)
+)
+
+// RegisterDialect makes a format available by name.
+func RegisterDialect(name string, f Format) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	if f == nil {
+		panic("doc: RegisterDialect format is nil")
+	}
+	name = strings.ToLower(name)
+	if _, dup := dialects[name]; dup {
+		panic("doc: RegisterDialect called twice for " + name)
+	}
+	dialects[name] = f
+}
+
+// LookupDialect answers the registered format with the given name.
+func LookupDialect(name string) (Format, bool) {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	f, ok := dialects[strings.ToLower(name)]
+	return f, ok
+}
+
+// Dialects returns a sorted list of the names of the registered formats.
+func Dialects() []string {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	list := make([]string, 0, len(dialects))
+	for name := range dialects {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func init() {
+	RegisterDialect(PostgresDialect, _postgresFormat)
+	RegisterDialect(MySQLDialect, _mysqlFormat)
+	RegisterDialect(SQLiteDialect, _sqliteFormat)
+}
+
+// Names of the built-in SQL dialects.
+const (
+	MySQLDialect    = "mysql"
+	PostgresDialect = "postgres"
+	SQLiteDialect   = "sqlite"
+)
+
+// ------------------------------------------------------------
+// SQL-FORMAT
+
+// sqlFormat formats expressions for a SQL database. Strings are
+// quoted and escaped, and field names are quoted identifiers.
+// Operators without a SQL equivalent (STARTSWITH, CONTAINS,
+// EXISTS) have no keyword, so formatting them is ErrUnsupported.
+// Bound placeholders are rendered as values; unbound ones
+// in the dialect's placeholder style. NULL is never a value,
+// since a = NULL is never true in SQL where it's a null test
+// in evaluation, so comparing to nil is ErrUnsupported; use
+// IS NULL instead.
+type sqlFormat struct {
+	keywords map[string]string
+	// quote surrounds identifiers.
+	quote string
+	// backslash is true if backslash is an escape character
+	// in string literals.
+	backslash bool
+	// falseValue and trueValue render booleans.
+	falseValue, trueValue string
+	// jsonb is true if nested paths reach into a JSONB column,
+	// otherwise they're table.column references and can't be indexed.
+	jsonb bool
+
+	time     func(time.Time) string
+	duration func(time.Duration) (string, error)
+	param    func(Param) (string, error)
+}
+
+func (f *sqlFormat) Keyword(s string) string {
+	return f.keywords[s]
+}
+
+func (f *sqlFormat) Value(v interface{}) (string, error) {
+	nv, err := evalNormalize(v)
+	if err != nil {
+		return "", err
+	}
+	switch t := nv.(type) {
+	case nil:
+		return "", newUnsupportedError("comparison with NULL, use " + IsNullKeyword)
+	case bool:
+		if t {
+			return f.trueValue, nil
+		}
+		return f.falseValue, nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return "", newUnsupportedError(fmt.Sprintf("value %v", t))
+		}
+		return strconv.FormatFloat(t, 'g', -1, 64), nil
+	case string:
+		return f.quoteString(t), nil
+	case time.Time:
+		return f.time(t), nil
+	case time.Duration:
+		return f.duration(t)
+	}
+	return "", newUnsupportedError(fmt.Sprintf("value %T", v))
+}
+
+func (f *sqlFormat) Param(p Param) (string, error) {
+	if p.Bound {
+		return f.Value(p.Value)
+	}
+	return f.param(p)
+}
+
+// Path renders a nested path as a quoted column followed by
+// JSONB operators when the dialect supports them, so "doc.tags[0]"
+// is "doc"->'tags'->>0 and compares as text. Otherwise the path
+// is a dotted table.column reference.
+func (f *sqlFormat) Path(p FieldPath) (string, error) {
+	if f.jsonb && len(p) > 1 && !p[0].IsIndex {
+		s, _ := f.Identifier(p[0].Name)
+		return s + p[1:].jsonbSteps(), nil
+	}
+	var sb strings.Builder
+	for i, seg := range p {
+		if seg.IsIndex {
+			return "", newUnsupportedError("array index in " + p.String())
+		}
+		if i > 0 {
+			sb.WriteString(".")
+		}
+		s, _ := f.Identifier(seg.Name)
+		sb.WriteString(s)
+	}
+	return sb.String(), nil
+}
+
+// Identifier answers name quoted, doubling any quotes inside it.
+func (f *sqlFormat) Identifier(name string) (string, error) {
+	return f.quote + strings.ReplaceAll(name, f.quote, f.quote+f.quote) + f.quote, nil
+}
+
+func (f *sqlFormat) quoteString(s string) string {
+	if f.backslash {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlKeywords answers the keywords shared by the dialects,
+// with the supplied additions.
+func sqlKeywords(more map[string]string) map[string]string {
+	keywords := map[string]string{
+		AndKeyword:          ` AND `,
+		AssignKeyword:       ` = `,
+		BetweenKeyword:      ` BETWEEN `,
+		EqualKeyword:        ` = `,
+		EscapeKeyword:       ` ESCAPE '\'`,
+		GreaterKeyword:      ` > `,
+		GreaterEqualKeyword: ` >= `,
+		InKeyword:           ` IN `,
+		IsNotNullKeyword:    ` IS NOT NULL`,
+		IsNullKeyword:       ` IS NULL`,
+		LessKeyword:         ` < `,
+		LessEqualKeyword:    ` <= `,
+		LikeKeyword:         ` LIKE `,
+		ListKeyword:         `, `,
+		NotEqualKeyword:     ` <> `,
+		NotInKeyword:        ` NOT IN `,
+		NotKeyword:          `NOT `,
+		OrKeyword:           ` OR `,
+		// Functions
+		"abs":   "ABS",
+		"lower": "LOWER",
+		"upper": "UPPER",
+	}
+	for k, v := range more {
+		keywords[k] = v
+	}
+	return keywords
+}
+
+func unsupportedDuration(d time.Duration) (string, error) {
+	return "", newUnsupportedError("duration " + d.String())
+}
+
+var (
+	// _postgresFormat assumes standard_conforming_strings,
+	// the default since PostgreSQL 9.1.
+	_postgresFormat = &sqlFormat{
+		keywords: sqlKeywords(map[string]string{
+			MatchesKeyword: ` ~ `,
+			"len":          "CHAR_LENGTH",
+			"now":          "NOW",
+		}),
+		quote:      `"`,
+		falseValue: "FALSE",
+		trueValue:  "TRUE",
+		jsonb:      true,
+		time: func(t time.Time) string {
+			return "TIMESTAMPTZ '" + t.Format("2006-01-02 15:04:05.999999Z07:00") + "'"
+		},
+		duration: func(d time.Duration) (string, error) {
+			return "INTERVAL '" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + " seconds'", nil
+		},
+		param: func(p Param) (string, error) {
+			if p.Index < 0 {
+				return "", newUnsupportedError("named placeholder " + p.Text)
+			}
+			return "$" + strconv.Itoa(p.Index+1), nil
+		},
+	}
+
+	// _mysqlFormat assumes the default sql_mode, where
+	// backslash is an escape character.
+	_mysqlFormat = &sqlFormat{
+		keywords: sqlKeywords(map[string]string{
+			// Backslash must itself be escaped in a MySQL string.
+			EscapeKeyword:  ` ESCAPE '\\'`,
+			MatchesKeyword: ` REGEXP `,
+			"len":          "CHAR_LENGTH",
+			"now":          "NOW",
+		}),
+		quote:      "`",
+		backslash:  true,
+		falseValue: "FALSE",
+		trueValue:  "TRUE",
+		time: func(t time.Time) string {
+			return "'" + t.UTC().Format("2006-01-02 15:04:05.999999") + "'"
+		},
+		duration: unsupportedDuration,
+		param: func(p Param) (string, error) {
+			if p.Text != "?" {
+				return "", newUnsupportedError("placeholder " + p.Text)
+			}
+			return "?", nil
+		},
+	}
+
+	// _sqliteFormat writes times as UTC text, which
+	// SQLite's date functions understand.
+	_sqliteFormat = &sqlFormat{
+		keywords: sqlKeywords(map[string]string{
+			MatchesKeyword: ` REGEXP `,
+			"len":          "LENGTH",
+			"now":          "DATETIME",
+		}),
+		quote:      `"`,
+		falseValue: "0",
+		trueValue:  "1",
+		time: func(t time.Time) string {
+			return "'" + t.UTC().Format("2006-01-02 15:04:05.999") + "'"
+		},
+		duration: unsupportedDuration,
+		param: func(p Param) (string, error) {
+			if p.Name != "" {
+				return ":" + p.Name, nil
+			}
+			return "?" + strconv.Itoa(p.Index+1), nil
+		},
+	}
+)
@@ -1,102 +1,230 @@
 package parser
 
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
 var (
-	badRequestErr = newBadRequestError("")
-	conditionErr  = newConditionError("")
-	evalErr       = newEvalError("")
-	syntaxErr     = newSyntaxError("")
-	malformedErr  = newMalformedError("")
-	mismatchErr   = newMismatchError("")
-	parseErr      = newParseError("")
-	unhandledErr  = newUnhandledError("")
+	ErrBadRequest = newBadRequestError("")
+	ErrCondition  = newConditionError("")
+	ErrEval       = newEvalError("")
+	ErrSyntax     = newSyntaxError("")
+	ErrMalformed  = newMalformedError("")
+	ErrMismatch   = newMismatchError("")
+	ErrParse      = newParseError("")
+	ErrUnhandled  = newUnhandledError("")
+	// ErrUnsupported is returned when a Format can't render an operator.
+	ErrUnsupported = newUnsupportedError("")
+
+	// Parsing errors when an expression exceeds the ParseOptions limits.
+	ErrTooLong       = &Error{Code: TooLongErrCode}
+	ErrTooManyTokens = &Error{Code: TooManyTokensErrCode}
+	ErrTooDeep       = &Error{Code: TooDeepErrCode}
+	ErrTooManyItems  = &Error{Code: TooManyItemsErrCode}
+	ErrTooManyFields = &Error{Code: TooManyFieldsErrCode}
 )
 
 // --------------------------------
-// SQI-ERROR
+// DOC-ERROR
 
 func newBadRequestError(msg string) error {
-	return &docErr{badRequestErrCode, msg, nil}
+	return &Error{Code: BadRequestErrCode, Msg: msg}
 }
 
 func newConditionError(msg string) error {
-	return &docErr{conditionErrCode, msg, nil}
+	return &Error{Code: ConditionErrCode, Msg: msg}
 }
 
 func newEvalError(msg string) error {
-	return &docErr{evalErrCode, msg, nil}
+	return &Error{Code: EvalErrCode, Msg: msg}
 }
 
 func newSyntaxError(msg string) error {
-	return &docErr{syntaxErrCode, msg, nil}
+	return &Error{Code: SyntaxErrCode, Msg: msg}
 }
 
 func newMalformedError(msg string) error {
-	return &docErr{malformedErrCode, msg, nil}
+	return &Error{Code: MalformedErrCode, Msg: msg}
 }
 
 func newMismatchError(msg string) error {
-	return &docErr{mismatchErrCode, msg, nil}
+	return &Error{Code: MismatchErrCode, Msg: msg}
 }
 
 func newParseError(msg string) error {
-	return &docErr{parseErrCode, msg, nil}
+	return &Error{Code: ParseErrCode, Msg: msg}
 }
 
 func newUnhandledError(msg string) error {
-	return &docErr{unhandledErrCode, msg, nil}
+	return &Error{Code: UnhandledErrCode, Msg: msg}
+}
+
+func newUnsupportedError(msg string) error {
+	return &Error{Code: UnsupportedErrCode, Msg: msg}
+}
+
+// newLimitError answers an error with code, for an
+// expression that exceeds limit.
+func newLimitError(code int, limit int) error {
+	return &Error{Code: code, Msg: "limit is " + strconv.Itoa(limit)}
+}
+
+// errorAt answers err located at node n. Errors that already
+// have a position are unchanged, so the innermost location wins.
+// Errors from outside this package are wrapped in a parse error.
+func errorAt(err error, n *nodeT) error {
+	if err == nil || n == nil {
+		return err
+	}
+	return errorAtPos(err, n.Pos, n.Text)
+}
+
+// errorAtPos answers err located at pos, near token.
+func errorAtPos(err error, pos Position, token string) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Code: ParseErrCode, Err: err}
+	} else if e.Pos.IsValid() {
+		return err
+	} else {
+		cp := *e
+		e = &cp
+	}
+	e.Pos = pos
+	e.Token = token
+	return e
+}
+
+// Error is the error produced by the parser. Use errors.Is()
+// with the Err* vars to test the kind of error.
+type Error struct {
+	Code int
+	Msg  string
+	// Pos and Token describe the location of the error in
+	// the source expression, if known.
+	Pos   Position
+	Token string
+	Err   error
 }
 
-type docErr struct {
-	code int
-	msg  string
-	err  error
+func (e *Error) ErrorCode() int {
+	return e.Code
 }
 
-func (e *docErr) ErrorCode() int {
-	return e.code
+// Is answers true if target is an *Error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
 }
 
-func (e *docErr) Error() string {
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Error() string {
 	var label string
-	switch e.code {
-	case badRequestErrCode:
+	switch e.Code {
+	case BadRequestErrCode:
 		label = "doc: bad request"
-	case conditionErrCode:
+	case ConditionErrCode:
 		label = "doc: condition"
-	case evalErrCode:
+	case EvalErrCode:
 		label = "doc: eval"
-	case syntaxErrCode:
+	case SyntaxErrCode:
 		label = "doc: invalid syntax"
-	case malformedErrCode:
+	case MalformedErrCode:
 		label = "doc: malformed"
-	case mismatchErrCode:
+	case MismatchErrCode:
 		label = "doc: mismatch"
-	case parseErrCode:
+	case ParseErrCode:
 		label = "doc: parse"
-	case unhandledErrCode:
+	case UnhandledErrCode:
 		label = "doc: unhandled"
+	case UnsupportedErrCode:
+		label = "doc: unsupported by format"
+	case TooLongErrCode:
+		label = "doc: expression too long"
+	case TooManyTokensErrCode:
+		label = "doc: too many tokens"
+	case TooDeepErrCode:
+		label = "doc: expression too deeply nested"
+	case TooManyItemsErrCode:
+		label = "doc: too many list items"
+	case TooManyFieldsErrCode:
+		label = "doc: too many fields"
 	default:
 		label = "doc: error"
 	}
-	if e.msg != "" {
-		label += " (" + e.msg + ")"
+	if e.Msg != "" {
+		label += " (" + e.Msg + ")"
+	}
+	if e.Err != nil {
+		label += " (" + e.Err.Error() + ")"
 	}
-	if e.err != nil {
-		label += " (" + e.err.Error() + ")"
+	if e.Pos.IsValid() {
+		label += " at " + e.Pos.String()
 	}
 	return label
 }
 
+// FormatError answers err as a string, followed by the line of
+// expr that contains the error and a caret under the error position.
+// Errors without a position are answered as-is.
+func FormatError(expr string, err error) string {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if !errors.As(err, &e) || !e.Pos.IsValid() || e.Pos.Offset > len(expr) {
+		return err.Error()
+	}
+	start := strings.LastIndex(expr[:e.Pos.Offset], "\n") + 1
+	end := strings.Index(expr[start:], "\n")
+	if end < 0 {
+		end = len(expr)
+	} else {
+		end += start
+	}
+	line := expr[start:end]
+
+	var sb strings.Builder
+	sb.WriteString(err.Error())
+	sb.WriteString("\n")
+	sb.WriteString(line)
+	sb.WriteString("\n")
+	// Preserve tabs so the caret lines up.
+	for _, r := range expr[start:e.Pos.Offset] {
+		if r == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+	sb.WriteString("^")
+	return sb.String()
+}
+
 // --------------------------------
 // CONST and VAR
 
 const (
-	badRequestErrCode = 1000 + iota
-	conditionErrCode
-	evalErrCode
-	syntaxErrCode
-	malformedErrCode
-	mismatchErrCode
-	parseErrCode
-	unhandledErrCode
+	BadRequestErrCode = 1000 + iota
+	ConditionErrCode
+	EvalErrCode
+	SyntaxErrCode
+	MalformedErrCode
+	MismatchErrCode
+	ParseErrCode
+	UnhandledErrCode
+	UnsupportedErrCode
+	TooLongErrCode
+	TooManyTokensErrCode
+	TooDeepErrCode
+	TooManyItemsErrCode
+	TooManyFieldsErrCode
 )
@@ -0,0 +1,379 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------------
+// EVAL
+
+// EvalArgs provides the state for evaluating an AST.
+type EvalArgs struct {
+	// Item is the value being evaluated against, i.e. the struct
+	// or map that supplies the fields.
+	Item any
+	Opt  Opt
+	Ctx  FormatContext
+}
+
+// Evaluator is implemented by nodes that can be evaluated.
+// All the nodes produced by this package are Evaluators.
+type Evaluator interface {
+	Eval(args EvalArgs) (any, error)
+}
+
+// Eval evaluates the AST against item, which must be a struct,
+// a map with string keys, or a pointer to either.
+func Eval(ast AstNode, item any, opt Opt) (any, error) {
+	if ast == nil {
+		return nil, newEvalError("missing AST")
+	}
+	return evalNode(ast, EvalArgs{Item: item, Opt: opt})
+}
+
+// EvalBool evaluates the AST and answers the result as a bool.
+func EvalBool(ast AstNode, item any, opt Opt) (bool, error) {
+	v, err := Eval(ast, item, opt)
+	if err != nil {
+		return opt.onErrorBool(), err
+	}
+	v, err = evalNormalize(v)
+	if err != nil {
+		return opt.onErrorBool(), err
+	}
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return opt.onErrorBool(), newMismatchError(fmt.Sprintf("have %T want bool", v))
+}
+
+// EvalFloat64 evaluates the AST and answers the result as a float64.
+func EvalFloat64(ast AstNode, item any, opt Opt) (float64, error) {
+	v, err := Eval(ast, item, opt)
+	if err != nil {
+		return opt.onErrorFloat64(), err
+	}
+	v, err = evalNormalize(v)
+	if err != nil {
+		return opt.onErrorFloat64(), err
+	}
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case int64:
+		if !opt.Strict {
+			return float64(t), nil
+		}
+	}
+	return opt.onErrorFloat64(), newMismatchError(fmt.Sprintf("have %T want float64", v))
+}
+
+// EvalInt evaluates the AST and answers the result as an int.
+func EvalInt(ast AstNode, item any, opt Opt) (int, error) {
+	v, err := Eval(ast, item, opt)
+	if err != nil {
+		return opt.onErrorInt(), err
+	}
+	v, err = evalNormalize(v)
+	if err != nil {
+		return opt.onErrorInt(), err
+	}
+	switch t := v.(type) {
+	case int64:
+		return int(t), nil
+	case float64:
+		if !opt.Strict && t == float64(int(t)) {
+			return int(t), nil
+		}
+	}
+	return opt.onErrorInt(), newMismatchError(fmt.Sprintf("have %T want int", v))
+}
+
+// EvalString evaluates the AST and answers the result as a string.
+func EvalString(ast AstNode, item any, opt Opt) (string, error) {
+	v, err := Eval(ast, item, opt)
+	if err != nil {
+		return opt.onErrorString(), err
+	}
+	v, err = evalNormalize(v)
+	if err != nil {
+		return opt.onErrorString(), err
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	if !opt.Strict && v != nil {
+		return fmt.Sprintf("%v", v), nil
+	}
+	return opt.onErrorString(), newMismatchError(fmt.Sprintf("have %T want string", v))
+}
+
+// EvalStringSlice evaluates the AST and answers the result as a []string.
+func EvalStringSlice(ast AstNode, item any, opt Opt) ([]string, error) {
+	v, err := Eval(ast, item, opt)
+	if err != nil {
+		return opt.onErrorStringSlice(), err
+	}
+	switch t := v.(type) {
+	case []string:
+		return t, nil
+	case []any:
+		ans := make([]string, 0, len(t))
+		for _, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				return opt.onErrorStringSlice(), newMismatchError(fmt.Sprintf("have %T want string", e))
+			}
+			ans = append(ans, s)
+		}
+		return ans, nil
+	}
+	return opt.onErrorStringSlice(), newMismatchError(fmt.Sprintf("have %T want []string", v))
+}
+
+// EvalStringInterfaceMap evaluates the AST and answers the result as a map[string]interface{}.
+func EvalStringInterfaceMap(ast AstNode, item any, opt Opt) (map[string]interface{}, error) {
+	v, err := Eval(ast, item, opt)
+	if err != nil {
+		return opt.onErrorStringInterfaceMap(), err
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return m, nil
+	}
+	return opt.onErrorStringInterfaceMap(), newMismatchError(fmt.Sprintf("have %T want map[string]interface{}", v))
+}
+
+// EvalStringStringMap evaluates the AST and answers the result as a map[string]string.
+func EvalStringStringMap(ast AstNode, item any, opt Opt) (map[string]string, error) {
+	v, err := Eval(ast, item, opt)
+	if err != nil {
+		return opt.onErrorStringStringMap(), err
+	}
+	if m, ok := v.(map[string]string); ok {
+		return m, nil
+	}
+	return opt.onErrorStringStringMap(), newMismatchError(fmt.Sprintf("have %T want map[string]string", v))
+}
+
+// ------------------------------------------------------------
+// SUPPORT
+
+// evalNode evaluates n, which must be an Evaluator.
+func evalNode(n AstNode, args EvalArgs) (any, error) {
+	e, ok := n.(Evaluator)
+	if !ok {
+		return nil, newUnsupportedError(fmt.Sprintf("%T can't be evaluated", n))
+	}
+	return e.Eval(args)
+}
+
+// evalBool answers v as a bool. Non-bools are false,
+// or an error in strict mode.
+func evalBool(v any, opt Opt) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	if opt.Strict {
+		return false, newMismatchError(fmt.Sprintf("have %T want bool", v))
+	}
+	return false, nil
+}
+
+// evalCompare answers the comparison of a and b as -1, 0 or 1.
+// The bool is false if the values can't be compared, in which
+// case strict mode produces an error.
+func evalCompare(a, b any, opt Opt) (int, bool, error) {
+	a, err := evalNormalize(a)
+	if err != nil {
+		return 0, false, err
+	}
+	b, err = evalNormalize(b)
+	if err != nil {
+		return 0, false, err
+	}
+	switch at := a.(type) {
+	case nil:
+		if b == nil {
+			return 0, true, nil
+		}
+	case int64:
+		switch bt := b.(type) {
+		case int64:
+			return compareOrdered(at, bt), true, nil
+		case float64:
+			return compareOrdered(float64(at), bt), true, nil
+		}
+	case float64:
+		switch bt := b.(type) {
+		case int64:
+			return compareOrdered(at, float64(bt)), true, nil
+		case float64:
+			return compareOrdered(at, bt), true, nil
+		}
+	case string:
+		if bt, ok := b.(string); ok {
+			return strings.Compare(at, bt), true, nil
+		}
+	case time.Time:
+		if bt, ok := b.(time.Time); ok {
+			return at.Compare(bt), true, nil
+		}
+	case time.Duration:
+		if bt, ok := b.(time.Duration); ok {
+			return compareOrdered(int64(at), int64(bt)), true, nil
+		}
+	case bool:
+		if bt, ok := b.(bool); ok && at == bt {
+			return 0, true, nil
+		} else if ok {
+			return compareOrdered(boolRank(at), boolRank(bt)), true, nil
+		}
+	}
+	if opt.Strict {
+		return 0, false, newMismatchError(fmt.Sprintf("can't compare %T to %T", a, b))
+	}
+	return 0, false, nil
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolRank(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// evalNormalize converts the numeric types to int64 and
+// float64, the types produced by the parser. Times and
+// durations are kept as-is. Unsigned values that don't
+// fit in an int64 are a mismatch.
+func evalNormalize(v any) (any, error) {
+	switch t := v.(type) {
+	case nil, int64, float64, string, bool, time.Time, time.Duration:
+		return v, nil
+	case int:
+		return int64(t), nil
+	case int8:
+		return int64(t), nil
+	case int16:
+		return int64(t), nil
+	case int32:
+		return int64(t), nil
+	case uint:
+		return evalUint(uint64(t))
+	case uint8:
+		return int64(t), nil
+	case uint16:
+		return int64(t), nil
+	case uint32:
+		return int64(t), nil
+	case uint64:
+		return evalUint(t)
+	case float32:
+		return float64(t), nil
+	}
+	// Named types, i.e. type Status string.
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return evalUint(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	}
+	return v, nil
+}
+
+// evalUint answers u as an int64, or a mismatch
+// error if it's above math.MaxInt64.
+func evalUint(u uint64) (any, error) {
+	if u > math.MaxInt64 {
+		return nil, newMismatchError(fmt.Sprintf("%v overflows int64", u))
+	}
+	return int64(u), nil
+}
+
+// evalField answers the named field from item, which can be
+// a struct or a map with string keys. Struct fields are matched
+// by their exact name, as map keys are.
+func evalField(item any, name string) (any, bool) {
+	rv := reflect.ValueOf(item)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !v.IsValid() {
+			return nil, false
+		}
+		return v.Interface(), true
+	case reflect.Struct:
+		sf, ok := rv.Type().FieldByName(name)
+		if !ok {
+			return nil, false
+		}
+		// A promoted field behind a nil embedded pointer is missing.
+		f, err := rv.FieldByIndexErr(sf.Index)
+		if err != nil || !f.CanInterface() {
+			return nil, false
+		}
+		return f.Interface(), true
+	}
+	return nil, false
+}
+
+// evalPath answers the value at path in item. Names are resolved
+// with evalField, and indexes apply to slices and arrays.
+func evalPath(item any, path FieldPath) (any, bool) {
+	v := item
+	for _, seg := range path {
+		if !seg.IsIndex {
+			var found bool
+			v, found = evalField(v, seg.Name)
+			if !found {
+				return nil, false
+			}
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return nil, false
+			}
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, false
+		}
+		if seg.Index >= rv.Len() || !rv.Index(seg.Index).CanInterface() {
+			return nil, false
+		}
+		v = rv.Index(seg.Index).Interface()
+	}
+	return v, true
+}
@@ -6,3 +6,49 @@ type ExtractBinary interface {
 	BinaryConjunction(keyword string) error
 	BinaryAssignment(lhs string, rhs any) error
 }
+
+// ExtractComparison receives the comparison operators
+// (==, !=, <, <=, >, >=). keyword is the operator.
+type ExtractComparison interface {
+	BinaryComparison(lhs string, keyword string, rhs any) error
+}
+
+// ExtractIn receives membership tests (IN, NOT IN).
+// keyword is the operator.
+type ExtractIn interface {
+	BinaryIn(lhs string, keyword string, rhs []any) error
+}
+
+// ExtractMatch receives pattern matches (LIKE, STARTSWITH,
+// CONTAINS, MATCHES). keyword is the operator.
+type ExtractMatch interface {
+	BinaryMatch(lhs string, keyword string, pattern any) error
+}
+
+// ExtractBetween receives range tests (BETWEEN).
+type ExtractBetween interface {
+	FieldBetween(lhs string, r Range) error
+}
+
+// ExtractPredicate receives field tests (IS NULL, IS NOT NULL,
+// EXISTS). keyword is the operator.
+type ExtractPredicate interface {
+	FieldPredicate(lhs string, keyword string) error
+}
+
+// ExtractNot receives NOT, before the condition it negates.
+// Extractors that don't implement it get ErrUnsupported for
+// a NOT, rather than the negated condition.
+type ExtractNot interface {
+	UnaryNot() error
+}
+
+// isExtractor answers true if fn implements any of the Extract
+// interfaces, so a construct it can't receive must be an error.
+func isExtractor(fn any) bool {
+	switch fn.(type) {
+	case ExtractBinary, ExtractComparison, ExtractIn, ExtractMatch, ExtractBetween, ExtractPredicate, ExtractNot:
+		return true
+	}
+	return false
+}
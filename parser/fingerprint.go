@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FingerprintOptions controls Fingerprint.
+type FingerprintOptions struct {
+	// AbstractValues replaces literal values and placeholders
+	// with a marker, so expressions with the same shape share
+	// a fingerprint, i.e. "id = 5" and "id = 6". Lists of
+	// values also share a fingerprint regardless of length.
+	AbstractValues bool
+}
+
+// Equal answers true if a and b are structurally identical.
+// Values must have the same type, so 1 and 1.0 are different.
+func Equal(a, b AstNode) bool {
+	return nodeKey(a) == nodeKey(b)
+}
+
+// Fingerprint answers a stable hash of the structure of n.
+// Identical trees always have the same fingerprint, across
+// processes and versions.
+func Fingerprint(n AstNode, opts FingerprintOptions) string {
+	kw := keyWriter{abstract: opts.AbstractValues}
+	kw.sb.WriteString(fingerprintVersion)
+	kw.write(n, NoFormatContext)
+	sum := sha256.Sum256([]byte(kw.sb.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// fingerprintVersion identifies the key encoding. Any change
+// to the encoding must change the version, so old fingerprints
+// are never confused with new ones.
+const fingerprintVersion = "v1:"
+
+// nodeKey answers a string that is identical for
+// structurally identical trees.
+func nodeKey(n AstNode) string {
+	kw := keyWriter{}
+	kw.write(n, NoFormatContext)
+	return kw.sb.String()
+}
+
+// ------------------------------------------------------------
+// KEY-WRITER
+
+// keyWriter writes the structure of a tree as an s-expression.
+// Contexts follow formatting, so the writer knows which strings
+// are fields and which are values.
+type keyWriter struct {
+	sb       strings.Builder
+	abstract bool
+}
+
+func (w *keyWriter) write(n AstNode, ctx FormatContext) {
+	switch t := n.(type) {
+	case nil:
+		w.sb.WriteString("nil")
+		return
+	case *valueNode:
+		if _, isString := t.Value.(string); w.abstract && (ctx == ValueContext || !isString) {
+			w.sb.WriteString("?")
+			return
+		}
+		w.value(t.Value)
+		return
+	case *paramNode:
+		if w.abstract {
+			w.sb.WriteString("?")
+			return
+		}
+		w.sb.WriteString("param:" + strconv.Quote(t.Param.Text))
+		if t.Param.Bound {
+			w.sb.WriteString("=")
+			// Values that can't be normalized keep their own type.
+			v := t.Param.Value
+			if nv, err := evalNormalize(v); err == nil {
+				v = nv
+			}
+			w.value(v)
+		}
+		return
+	case *fieldNode:
+		if w.abstract && ctx == ValueContext {
+			w.sb.WriteString("?")
+			return
+		}
+		w.sb.WriteString("field:" + strconv.Quote(t.Path.String()))
+		return
+	case *listNode:
+		if w.abstract && w.allAbstract(t.Items, ctx) {
+			w.sb.WriteString("(list ?)")
+			return
+		}
+	}
+
+	w.sb.WriteString("(")
+	switch t := n.(type) {
+	case *binaryNode:
+		w.sb.WriteString(t.Keyword)
+	case *unaryNode:
+		w.sb.WriteString(tokenMap[t.Op].Text)
+	case *listNode:
+		w.sb.WriteString("list")
+	case *matchNode:
+		w.sb.WriteString(t.Keyword)
+	case *predicateNode:
+		w.sb.WriteString(t.Keyword)
+	case *betweenNode:
+		fmt.Fprintf(&w.sb, "%v:%v:%v", t.Keyword, t.LowerExclusive, t.UpperExclusive)
+	case *callNode:
+		w.sb.WriteString(strconv.Quote(t.Name) + "()")
+	default:
+		fmt.Fprintf(&w.sb, "%T", n)
+	}
+	ctxs := childContexts(n, ctx)
+	for i, kid := range children(n) {
+		w.sb.WriteString(" ")
+		w.write(kid, ctxs[i])
+	}
+	w.sb.WriteString(")")
+}
+
+// value writes v with its type. Strings are quoted, so
+// no value can be mistaken for structure.
+func (w *keyWriter) value(v any) {
+	fmt.Fprintf(&w.sb, "%T:", v)
+	switch t := v.(type) {
+	case nil, bool, int64:
+		fmt.Fprintf(&w.sb, "%v", t)
+	case float64:
+		w.sb.WriteString(strconv.FormatFloat(t, 'g', -1, 64))
+	case string:
+		w.sb.WriteString(strconv.Quote(t))
+	case time.Time:
+		w.sb.WriteString(t.Format(time.RFC3339Nano))
+	case time.Duration:
+		w.sb.WriteString(strconv.FormatInt(int64(t), 10))
+	default:
+		w.sb.WriteString(strconv.Quote(fmt.Sprintf("%v", t)))
+	}
+}
+
+// allAbstract answers true if every item would be written as a marker.
+func (w *keyWriter) allAbstract(items []AstNode, ctx FormatContext) bool {
+	for _, item := range items {
+		switch t := item.(type) {
+		case *paramNode:
+		case *valueNode:
+			if _, isString := t.Value.(string); isString && ctx != ValueContext {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"time"
 )
 
 // Format provides the tokens and rules
@@ -15,6 +16,26 @@ type Format interface {
 	Value(v interface{}) (string, error)
 }
 
+// ParamFormat is an optional extension to Format for
+// rendering placeholders. Drivers can emit their native
+// placeholder syntax, or render the bound value. When the
+// Format doesn't implement it, bound placeholders are
+// rendered with Value and unbound ones as written.
+type ParamFormat interface {
+	Param(p Param) (string, error)
+}
+
+// formatParam answers p rendered by f.
+func formatParam(p Param, f Format) (string, error) {
+	if pf, ok := f.(ParamFormat); ok {
+		return pf.Param(p)
+	}
+	if p.Bound {
+		return f.Value(p.Value)
+	}
+	return p.Text, nil
+}
+
 func DefaultFormat() Format {
 	return _defaultFormat
 }
@@ -30,5 +51,13 @@ func (f *_format) Keyword(s string) string {
 }
 
 func (f *_format) Value(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return NullKeyword, nil
+	case time.Time:
+		return `t"` + t.Format(time.RFC3339Nano) + `"`, nil
+	case time.Duration:
+		return `d"` + t.String() + `"`, nil
+	}
 	return fmt.Sprintf("%v", v), nil
 }
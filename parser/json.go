@@ -0,0 +1,352 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonVersion is the version of the JSON document. It changes
+// whenever a document could be read differently.
+const jsonVersion = 1
+
+// Ops in the JSON document that aren't keywords.
+const (
+	jsonCall  = "CALL"
+	jsonField = "FIELD"
+	jsonGroup = "GROUP"
+	jsonList  = "LIST"
+	jsonNeg   = "-"
+	jsonParam = "PARAM"
+	jsonValue = "VALUE"
+)
+
+// Literal types in the JSON document.
+const (
+	jsonBool     = "bool"
+	jsonDuration = "duration"
+	jsonFloat    = "float"
+	jsonInt      = "int"
+	jsonNull     = "null"
+	jsonString   = "string"
+	jsonTime     = "time"
+)
+
+// jsonDoc is the top level of the JSON document.
+type jsonDoc struct {
+	Version int       `json:"version"`
+	Ast     *jsonNode `json:"ast"`
+}
+
+// jsonNode is a single AST node. Op is the keyword for operators,
+// or one of the json* ops. Operands are in Args, in the order
+// they appear in the expression.
+type jsonNode struct {
+	Op   string      `json:"op"`
+	Args []*jsonNode `json:"args,omitempty"`
+
+	// FIELD, as a path in dot notation, or as a single Name
+	// if it has characters that would make it a path.
+	Field string `json:"field,omitempty"`
+	// VALUE, and PARAM when bound
+	Type  string          `json:"type,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	// PARAM
+	Param string `json:"param,omitempty"`
+	// CALL, and FIELD
+	Name string `json:"name,omitempty"`
+	// BETWEEN
+	LowerExclusive bool `json:"lowerExclusive,omitempty"`
+	UpperExclusive bool `json:"upperExclusive,omitempty"`
+}
+
+// MarshalAst answers n as a versioned JSON document. Fields
+// and literals are distinguished, and literals carry their type.
+// Numbers are normalized to int64 and float64.
+func MarshalAst(n AstNode) ([]byte, error) {
+	jn, err := toJsonNode(n, NoFormatContext)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonDoc{Version: jsonVersion, Ast: jn})
+}
+
+// UnmarshalAst answers the AST in a document created by MarshalAst.
+// The tree is checked as if it had been parsed, so unknown functions
+// and malformed operators are errors.
+func UnmarshalAst(data []byte) (AstNode, error) {
+	var doc jsonDoc
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, &Error{Code: BadRequestErrCode, Msg: "invalid json", Err: err}
+	}
+	if doc.Version != jsonVersion {
+		return nil, newUnsupportedError("json version " + strconv.Itoa(doc.Version))
+	}
+	if doc.Ast == nil {
+		return nil, newBadRequestError("json missing ast")
+	}
+	ast, err := doc.Ast.asAst()
+	if err != nil {
+		return nil, err
+	}
+	return ast, indexParams(ast)
+}
+
+// ------------------------------------------------------------
+// MARSHAL
+
+func toJsonNode(n AstNode, ctx FormatContext) (*jsonNode, error) {
+	switch t := n.(type) {
+	case *valueNode:
+		if s, ok := t.Value.(string); ok && ctx != ValueContext {
+			if strings.ContainsAny(s, ".[") {
+				return &jsonNode{Op: jsonField, Name: s}, nil
+			}
+			return &jsonNode{Op: jsonField, Field: s}, nil
+		}
+		jn := &jsonNode{Op: jsonValue}
+		return jn, jn.setValue(t.Value)
+	case *fieldNode:
+		return &jsonNode{Op: jsonField, Field: t.Path.String()}, nil
+	case *paramNode:
+		jn := &jsonNode{Op: jsonParam, Param: t.Param.Text}
+		if t.Param.Bound {
+			return jn, jn.setValue(t.Param.Value)
+		}
+		return jn, nil
+	}
+
+	var jn *jsonNode
+	switch t := n.(type) {
+	case *binaryNode:
+		jn = &jsonNode{Op: t.Keyword}
+	case *unaryNode:
+		switch t.Op {
+		case openToken:
+			jn = &jsonNode{Op: jsonGroup}
+		case negToken:
+			jn = &jsonNode{Op: jsonNeg}
+		default:
+			jn = &jsonNode{Op: t.Keyword}
+		}
+	case *listNode:
+		jn = &jsonNode{Op: jsonList}
+	case *matchNode:
+		jn = &jsonNode{Op: t.Keyword}
+	case *predicateNode:
+		jn = &jsonNode{Op: t.Keyword}
+	case *betweenNode:
+		jn = &jsonNode{Op: t.Keyword, LowerExclusive: t.LowerExclusive, UpperExclusive: t.UpperExclusive}
+	case *callNode:
+		jn = &jsonNode{Op: jsonCall, Name: t.Name}
+	default:
+		return nil, newUnhandledError(fmt.Sprintf("json on %T", n))
+	}
+	ctxs := childContexts(n, ctx)
+	for i, kid := range children(n) {
+		arg, err := toJsonNode(kid, ctxs[i])
+		if err != nil {
+			return nil, err
+		}
+		jn.Args = append(jn.Args, arg)
+	}
+	return jn, nil
+}
+
+// setValue sets my Type and Value from the literal v.
+func (jn *jsonNode) setValue(v any) error {
+	nv, err := evalNormalize(v)
+	if err != nil {
+		return err
+	}
+	var raw any
+	switch t := nv.(type) {
+	case nil:
+		jn.Type = jsonNull
+		return nil
+	case bool:
+		jn.Type, raw = jsonBool, t
+	case int64:
+		jn.Type, raw = jsonInt, t
+	case float64:
+		jn.Type, raw = jsonFloat, t
+	case string:
+		jn.Type, raw = jsonString, t
+	case time.Time:
+		jn.Type, raw = jsonTime, t.Format(time.RFC3339Nano)
+	case time.Duration:
+		jn.Type, raw = jsonDuration, t.String()
+	default:
+		return newUnsupportedError(fmt.Sprintf("json value %T", v))
+	}
+	data, err := json.Marshal(raw)
+	jn.Value = data
+	return err
+}
+
+// ------------------------------------------------------------
+// UNMARSHAL
+
+func (jn *jsonNode) asAst() (AstNode, error) {
+	args := make([]AstNode, 0, len(jn.Args))
+	for _, arg := range jn.Args {
+		if arg == nil {
+			return nil, newBadRequestError("json " + jn.Op + " has a null arg")
+		}
+		n, err := arg.asAst()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, n)
+	}
+
+	switch jn.Op {
+	case jsonField:
+		if err := jn.wantArgs(0); err != nil {
+			return nil, err
+		}
+		if jn.Name != "" {
+			if jn.Field != "" {
+				return nil, newBadRequestError("json FIELD has both a field and a name")
+			}
+			return &valueNode{Value: jn.Name}, nil
+		}
+		return NewField(jn.Field)
+	case jsonValue:
+		if err := jn.wantArgs(0); err != nil {
+			return nil, err
+		}
+		v, err := jn.value()
+		if err != nil {
+			return nil, err
+		}
+		return &valueNode{Value: v}, nil
+	case jsonParam:
+		if err := jn.wantArgs(0); err != nil {
+			return nil, err
+		}
+		param, err := newParam(jn.Param)
+		if err != nil {
+			return nil, err
+		}
+		if jn.Type != "" {
+			if param.Value, err = jn.value(); err != nil {
+				return nil, err
+			}
+			param.Bound = true
+		}
+		return &paramNode{Param: param}, nil
+	case jsonGroup, NotKeyword, jsonNeg:
+		if err := jn.wantArgs(1); err != nil {
+			return nil, err
+		}
+		switch jn.Op {
+		case jsonGroup:
+			return &unaryNode{Op: openToken, Child: args[0]}, nil
+		case jsonNeg:
+			return &unaryNode{Op: negToken, Child: args[0]}, nil
+		}
+		return &unaryNode{Op: notToken, Keyword: NotKeyword, Child: args[0]}, nil
+	case jsonList:
+		return &listNode{Items: args}, nil
+	case jsonCall:
+		return newCallNode(jn.Name, args, Position{})
+	case BetweenKeyword:
+		if err := jn.wantArgs(3); err != nil {
+			return nil, err
+		}
+		if _, err := fieldName(args[0]); err != nil {
+			return nil, newBadRequestError(jn.Op + " requires a field")
+		}
+		return &betweenNode{Keyword: BetweenKeyword, Field: args[0], Lower: args[1], Upper: args[2], LowerExclusive: jn.LowerExclusive, UpperExclusive: jn.UpperExclusive}, nil
+	}
+
+	token, ok := keywordOps[jn.Op]
+	if !ok {
+		return nil, newBadRequestError("json has unknown op " + strconv.Quote(jn.Op))
+	}
+	switch token.Symbol {
+	case isNullToken, isNotNullToken, existsToken:
+		if err := jn.wantArgs(1); err != nil {
+			return nil, err
+		}
+		return newPredicateNode(token.Symbol, token.Text, args[0])
+	}
+	if err := jn.wantArgs(2); err != nil {
+		return nil, err
+	}
+	switch token.Symbol {
+	case likeToken, startsWithToken, containsToken, matchesToken:
+		return newMatchNode(token.Symbol, token.Text, args[0], args[1])
+	case inToken, notInToken:
+		if _, ok := args[1].(*listNode); !ok {
+			return nil, newBadRequestError("json " + jn.Op + " requires a list")
+		}
+	}
+	return &binaryNode{Op: token.Symbol, Keyword: token.Text, Lhs: args[0], Rhs: args[1]}, nil
+}
+
+func (jn *jsonNode) wantArgs(n int) error {
+	if len(jn.Args) != n {
+		return newBadRequestError(fmt.Sprintf("json %v has %v args, wanted %v", jn.Op, len(jn.Args), n))
+	}
+	return nil
+}
+
+// value answers my literal, converted from Type.
+func (jn *jsonNode) value() (any, error) {
+	if jn.Type == jsonNull {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(jn.Value))
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, &Error{Code: BadRequestErrCode, Msg: "invalid json value", Err: err}
+	}
+	var err error
+	var v any
+	switch jn.Type {
+	case jsonBool:
+		b, ok := raw.(bool)
+		if !ok {
+			err = fmt.Errorf("not a bool")
+		}
+		v = b
+	case jsonInt:
+		if num, ok := raw.(json.Number); ok {
+			v, err = strconv.ParseInt(string(num), 10, 64)
+		} else {
+			err = fmt.Errorf("not a number")
+		}
+	case jsonFloat:
+		if num, ok := raw.(json.Number); ok {
+			v, err = strconv.ParseFloat(string(num), 64)
+		} else {
+			err = fmt.Errorf("not a number")
+		}
+	case jsonString:
+		s, ok := raw.(string)
+		if !ok {
+			err = fmt.Errorf("not a string")
+		}
+		v = s
+	case jsonTime:
+		s, _ := raw.(string)
+		v, err = time.Parse(time.RFC3339Nano, s)
+	case jsonDuration:
+		s, _ := raw.(string)
+		v, err = time.ParseDuration(s)
+	default:
+		return nil, newBadRequestError("json has unknown type " + strconv.Quote(jn.Type))
+	}
+	if err != nil {
+		return nil, &Error{Code: BadRequestErrCode, Msg: "invalid json " + jn.Type + " value", Err: err}
+	}
+	return v, nil
+}
@@ -8,34 +8,58 @@ import (
 )
 
 // scan converts a string into a flat list of tokens.
-func scan(input string) ([]*nodeT, error) {
+func scan(input string, opts ParseOptions) ([]*nodeT, error) {
 	var lexer scanner.Scanner
 	lexer.Init(strings.NewReader(input))
 	// lexer.Whitespace = 1<<'\r' | 1<<'\t'
 	lexer.Whitespace = 0
 	lexer.Mode = scanner.ScanChars | scanner.ScanComments | scanner.ScanFloats | scanner.ScanIdents | scanner.ScanInts | scanner.ScanRawStrings | scanner.ScanStrings
 
-	runer := &runerT{}
+	runer := &runerT{lenient: opts.Lenient, maxTokens: opts.maxTokens()}
 	lexer.IsIdentRune = runer.isIdentRune
 	lexer.Error = runer.handleScannerError
 	for tok := lexer.Scan(); tok != scanner.EOF; tok = lexer.Scan() {
 		if runer.err != nil {
 			return nil, runer.err
 		}
+		runer.pos = newPosition(lexer.Position)
 		// fmt.Println("TOK", tok, "text", lexer.TokenText())
 		switch tok {
 		case scanner.Float:
 			runer.flush()
 			runer.addToken(newNode(floatToken, lexer.TokenText()))
 		case scanner.Int:
+			if runer.param == '$' {
+				runer.addParam(lexer.TokenText())
+				continue
+			}
 			runer.flush()
 			runer.addToken(newNode(intToken, lexer.TokenText()))
 		case scanner.Ident:
+			if runer.param == ':' {
+				runer.addParam(lexer.TokenText())
+				continue
+			}
 			runer.flush()
+			// A prefix directly on a string is a typed literal, i.e. t"...".
+			if lexer.Peek() == '"' && runer.startLiteral(lexer.TokenText()) {
+				continue
+			}
 			runer.addString(lexer.TokenText())
 		case scanner.String:
+			if runer.literal != illegalToken {
+				runer.addLiteral(lexer.TokenText())
+				continue
+			}
 			runer.flush()
 			runer.addString(lexer.TokenText())
+		case scanner.Char, scanner.RawString:
+			// Only double quotes delimit strings.
+			runer.flush()
+			if runer.err == nil {
+				text := lexer.TokenText()
+				runer.err = errorAtPos(newSyntaxError("unsupported quote "+text[:1]+", use \""), runer.pos, text)
+			}
 		case ' ', '\r', '\t', '\n': // whitespace
 			runer.flush()
 		case scanner.Comment:
@@ -53,9 +77,25 @@ func scan(input string) ([]*nodeT, error) {
 
 // runerT supplies the rules for turning runes into nodes.
 type runerT struct {
-	accum  []rune
-	tokens []*nodeT
-	err    error
+	accum   []rune
+	tokens  []*nodeT
+	err     error
+	lenient bool
+	// maxTokens is the limit on the number of tokens.
+	maxTokens int
+	// param is the prefix of a placeholder ($ or :) waiting for its name.
+	param rune
+	// literal is the type of a prefixed literal waiting for its string.
+	literal symbol
+	// indexDepth is the count of open brackets in the current ident.
+	indexDepth int
+
+	// Positions of the current scanner token, the first
+	// accumulated rune, the placeholder prefix and the literal prefix.
+	pos        Position
+	accumPos   Position
+	paramPos   Position
+	literalPos Position
 }
 
 func (r *runerT) isIdentRune(ch rune, i int) bool {
@@ -64,7 +104,25 @@ func (r *runerT) isIdentRune(ch rune, i int) bool {
 	// when there's no whitespace separating them from idents, but I can't
 	// see any way the scanner would support that behaviour.
 	systemident := ch == '_' || unicode.IsLetter(ch) || unicode.IsDigit(ch) && i > 0
-	return systemident
+	// Field paths, i.e. "address.city" and "tags[0]". A close
+	// bracket only belongs to the ident if it closes an index,
+	// so "[a, b]" still ends in a bracket token.
+	if i == 0 {
+		r.indexDepth = 0
+	}
+	path := false
+	switch {
+	case i == 0:
+	case ch == '.':
+		path = true
+	case ch == '[':
+		r.indexDepth++
+		path = true
+	case ch == ']' && r.indexDepth > 0:
+		r.indexDepth--
+		path = true
+	}
+	return systemident || path
 }
 
 func (r *runerT) addString(s string) {
@@ -72,7 +130,45 @@ func (r *runerT) addString(s string) {
 }
 
 func (r *runerT) addToken(t *nodeT) {
-	r.tokens = append(r.tokens, t.reclassify())
+	r.addTokenAt(t, r.pos)
+}
+
+func (r *runerT) addTokenAt(t *nodeT, pos Position) {
+	if len(r.tokens) >= r.maxTokens {
+		if r.err == nil {
+			r.err = errorAtPos(newLimitError(TooManyTokensErrCode, r.maxTokens), pos, t.Text)
+		}
+		return
+	}
+	t = t.reclassify()
+	t.Pos = pos
+	r.tokens = append(r.tokens, t)
+}
+
+func (r *runerT) addParam(s string) {
+	r.addTokenAt(newNode(paramToken, string(r.param)+s), r.paramPos)
+	r.param = 0
+}
+
+// startLiteral begins a typed literal if prefix is one of the
+// literal prefixes (t for timestamps, d for durations).
+func (r *runerT) startLiteral(prefix string) bool {
+	switch prefix {
+	case "t":
+		r.literal = timeToken
+	case "d":
+		r.literal = durationToken
+	default:
+		return false
+	}
+	r.literalPos = r.pos
+	return true
+}
+
+// addLiteral adds the quoted string s as the pending typed literal.
+func (r *runerT) addLiteral(s string) {
+	r.addTokenAt(newNode(r.literal, strings.Trim(s, `"`)), r.literalPos)
+	r.literal = illegalToken
 }
 
 func (r *runerT) accumulate(ch rune) {
@@ -81,12 +177,25 @@ func (r *runerT) accumulate(ch rune) {
 	case '/':
 		r.flush()
 		r.addString(string(ch))
+	case '?':
+		r.flush()
+		r.addToken(newNode(paramToken, string(ch)))
+	case '$', ':':
+		r.flush()
+		r.param = ch
+		r.paramPos = r.pos
 	default:
+		if len(r.accum) < 1 {
+			r.accumPos = r.pos
+		}
 		r.accum = append(r.accum, ch)
 	}
 }
 
 func (r *runerT) flush() {
+	if r.err == nil && r.param != 0 {
+		r.err = errorAtPos(newSyntaxError("placeholder "+string(r.param)+" is missing a name"), r.paramPos, string(r.param))
+	}
 	if r.err != nil || len(r.accum) < 1 {
 		return
 	}
@@ -94,18 +203,23 @@ func (r *runerT) flush() {
 	// and place the remained in a string.
 	accum := string(r.accum)
 	lastAccum := accum
+	pos := r.accumPos
 	for accum != "" {
 		tok, s := r.extractToken(accum)
-		if tok == nil {
-			r.addToken(newNode(stringToken, s))
+		if tok == nil && !r.lenient {
+			r.err = errorAtPos(newSyntaxError("unknown operator "+s), pos, s)
+			return
+		} else if tok == nil {
+			r.addTokenAt(newNode(stringToken, s), pos)
 			accum = ""
 		} else {
-			r.addToken(newNode(tok.Symbol, tok.Text))
+			r.addTokenAt(newNode(tok.Symbol, tok.Text), pos)
 			accum = s
 		}
+		pos = pos.advance(lastAccum[:len(lastAccum)-len(accum)])
 		// Not sure if this is the best way to identify an infinite loop.
 		if accum == lastAccum {
-			r.err = newSyntaxError(r.stateAsString())
+			r.err = errorAtPos(newSyntaxError(r.stateAsString()), pos, accum)
 			return
 		}
 		lastAccum = accum
@@ -127,8 +241,11 @@ func (r *runerT) extractToken(s string) (*tokenT, string) {
 }
 
 func (r *runerT) handleScannerError(s *scanner.Scanner, msg string) {
+	if msg == "invalid char literal" {
+		msg = "unsupported quote ', use \""
+	}
 	if r.err == nil {
-		r.err = newSyntaxError(msg)
+		r.err = errorAtPos(newSyntaxError(msg), newPosition(s.Position), s.TokenText())
 	}
 }
 
@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ------------------------------------------------------------
+// MATCH-NODE
+
+// matchNode performs pattern matching on a string field
+// (LIKE, STARTSWITH, CONTAINS, MATCHES).
+type matchNode struct {
+	Op      symbol
+	Keyword string
+	Lhs     AstNode
+	Pattern AstNode
+
+	// re is the compiled pattern, for literal patterns.
+	re *regexp.Regexp
+}
+
+func newMatchNode(op symbol, keyword string, lhs, pattern AstNode) (*matchNode, error) {
+	n := &matchNode{Op: op, Keyword: keyword, Lhs: lhs, Pattern: pattern}
+	if err := n.stateErr(); err != nil {
+		return nil, err
+	}
+	if v, ok := pattern.(*valueNode); ok {
+		s, ok := v.Value.(string)
+		if !ok {
+			return nil, newSyntaxError(keyword + " requires a string pattern")
+		}
+		re, err := n.compile(s)
+		if err != nil {
+			return nil, err
+		}
+		n.re = re
+	}
+	return n, nil
+}
+
+func (n *matchNode) Format(args FormatArgs) error {
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+	keyword := args.Format.Keyword(n.Keyword)
+	if keyword == "" {
+		return newUnsupportedError(n.Keyword)
+	}
+
+	newArgs := args
+	newArgs.Ctx = NoFormatContext
+	err := formatChild(n.Lhs, newArgs, operandNeedsParens(n.Lhs))
+	if err != nil {
+		return err
+	}
+	args.Writer.WriteString(keyword)
+	newArgs.Ctx = ValueContext
+	if err = n.Pattern.Format(newArgs); err != nil {
+		return err
+	}
+	// LIKE escapes with backslash when evaluated, so say so.
+	if n.Keyword == LikeKeyword {
+		args.Writer.WriteString(args.Format.Keyword(EscapeKeyword))
+	}
+	return nil
+}
+
+func (n *matchNode) Fields(args *FieldArgs) error {
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+
+	prevctx := args.Ctx
+	defer func() { args.Ctx = prevctx }()
+	args.Ctx = NoFormatContext
+	err := n.Lhs.Fields(args)
+	args.Ctx = ValueContext
+	return cmp.Or(err, n.Pattern.Fields(args))
+}
+
+func (n *matchNode) Extract(fn any) error {
+	em, ok := fn.(ExtractMatch)
+	if !ok {
+		return nil
+	}
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+	lhs, err := fieldName(n.Lhs)
+	if err != nil {
+		return err
+	}
+	pattern, ok := valueOf(n.Pattern)
+	if !ok {
+		return newMalformedError(n.Keyword + " pattern is not a value")
+	}
+	return em.BinaryMatch(lhs, n.Keyword, pattern)
+}
+
+func (n *matchNode) Eval(args EvalArgs) (any, error) {
+	if err := n.stateErr(); err != nil {
+		return nil, err
+	}
+
+	lhsArgs, rhsArgs := args, args
+	lhsArgs.Ctx, rhsArgs.Ctx = NoFormatContext, ValueContext
+	lhs, err := evalNode(n.Lhs, lhsArgs)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := evalNode(n.Pattern, rhsArgs)
+	if err != nil {
+		return nil, err
+	}
+	p, err := evalNormalize(rhs)
+	if err != nil {
+		return nil, err
+	}
+	pattern, ok := p.(string)
+	if !ok {
+		return nil, newMismatchError(fmt.Sprintf("%v pattern is %T", n.Keyword, rhs))
+	}
+
+	// CONTAINS also tests for membership in a slice.
+	if n.Op == containsToken {
+		rv := reflect.ValueOf(lhs)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			for i := 0; i < rv.Len(); i++ {
+				if v, err := evalNormalize(rv.Index(i).Interface()); err == nil && v == pattern {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	}
+
+	l, err := evalNormalize(lhs)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := l.(string)
+	if !ok {
+		if args.Opt.Strict {
+			return nil, newMismatchError(fmt.Sprintf("%v requires a string, have %T", n.Keyword, lhs))
+		}
+		return false, nil
+	}
+	switch n.Op {
+	case startsWithToken:
+		return strings.HasPrefix(s, pattern), nil
+	case containsToken:
+		return strings.Contains(s, pattern), nil
+	}
+	re := n.re
+	if re == nil {
+		re, err = n.compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return re.MatchString(s), nil
+}
+
+func (n *matchNode) stateErr() error {
+	if n.Lhs == nil || n.Pattern == nil {
+		return newMalformedError("match node")
+	}
+	if n.Keyword == "" {
+		return newMalformedError("match node missing keyword")
+	}
+	return nil
+}
+
+// compile answers the regular expression for the pattern. Only
+// LIKE and MATCHES use regular expressions; the other operators
+// answer nil.
+func (n *matchNode) compile(pattern string) (*regexp.Regexp, error) {
+	switch n.Op {
+	case likeToken:
+		pattern = likeToRegexp(pattern)
+	case matchesToken:
+	default:
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, &Error{Code: SyntaxErrCode, Msg: "invalid " + n.Keyword + " pattern", Err: err}
+	}
+	return re, nil
+}
+
+// likeToRegexp converts a SQL LIKE pattern to a regular expression.
+// % matches any run of characters, _ matches a single character,
+// and \ escapes the following character.
+func likeToRegexp(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString(`(?s)^`)
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			sb.WriteString(`.*`)
+		case r == '_':
+			sb.WriteString(`.`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString(`$`)
+	return sb.String()
+}
@@ -0,0 +1,234 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ------------------------------------------------------------
+// MONGO
+
+// MongoFilter answers n as a MongoDB query filter, i.e.
+// {"$and": [{"age": {"$gte": 21}}, ...]}. Documents are maps,
+// arrays are []any and values keep their Go types, so the answer
+// can be encoded directly as BSON. Constructs with no filter
+// equivalent, such as comparing two fields or calling a
+// function, are ErrUnsupported.
+//
+// A bare field tests for true. NOT becomes $nor, so a missing
+// field satisfies NOT a = 1, as it does in evaluation.
+func MongoFilter(n AstNode) (map[string]any, error) {
+	switch t := n.(type) {
+	case *unaryNode:
+		switch t.Op {
+		case openToken:
+			return MongoFilter(t.Child)
+		case notToken:
+			child, err := MongoFilter(t.Child)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"$nor": []any{child}}, nil
+		}
+	case *binaryNode:
+		switch t.Op {
+		case andToken, orToken:
+			return mongoJoin(t)
+		case inToken, notInToken:
+			list, ok := t.Rhs.(*listNode)
+			if !ok {
+				return nil, newMalformedError(t.Keyword + " missing list")
+			}
+			values := make([]any, 0, len(list.Items))
+			for _, item := range list.Items {
+				v, err := mongoValue(item)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, v)
+			}
+			op := "$in"
+			if t.Op == notInToken {
+				op = "$nin"
+			}
+			return mongoField(t.Lhs, op, values)
+		}
+		if op, ok := mongoOps[t.Op]; ok {
+			v, err := mongoValue(t.Rhs)
+			if err != nil {
+				return nil, err
+			}
+			return mongoField(t.Lhs, op, v)
+		}
+	case *valueNode:
+		if b, ok := t.Value.(bool); ok {
+			// An empty filter matches everything.
+			if b {
+				return map[string]any{}, nil
+			}
+			return map[string]any{"$expr": false}, nil
+		}
+		return mongoField(n, "$eq", true)
+	case *fieldNode:
+		return mongoField(n, "$eq", true)
+	case *matchNode:
+		return mongoMatch(t)
+	case *predicateNode:
+		path, err := mongoPath(t.Field)
+		if err != nil {
+			return nil, err
+		}
+		switch t.Op {
+		case isNullToken:
+			return map[string]any{path: map[string]any{"$type": "null"}}, nil
+		case isNotNullToken:
+			return map[string]any{path: map[string]any{"$exists": true, "$ne": nil}}, nil
+		case existsToken:
+			return map[string]any{path: map[string]any{"$exists": true}}, nil
+		}
+	case *betweenNode:
+		path, err := mongoPath(t.Field)
+		if err != nil {
+			return nil, err
+		}
+		lower, err := mongoValue(t.Lower)
+		if err != nil {
+			return nil, err
+		}
+		upper, err := mongoValue(t.Upper)
+		if err != nil {
+			return nil, err
+		}
+		lowerOp, upperOp := "$gte", "$lte"
+		if t.LowerExclusive {
+			lowerOp = "$gt"
+		}
+		if t.UpperExclusive {
+			upperOp = "$lt"
+		}
+		return map[string]any{path: map[string]any{lowerOp: lower, upperOp: upper}}, nil
+	case *callNode:
+		return nil, newUnsupportedError("mongo filter on function " + t.Name)
+	}
+	return nil, newUnsupportedError("mongo filter on " + mongoName(n))
+}
+
+// mongoJoin answers a chain of AND or OR as a single $and or $or.
+func mongoJoin(n *binaryNode) (map[string]any, error) {
+	op := "$and"
+	if n.Op == orToken {
+		op = "$or"
+	}
+	terms := operands(n, n.Op)
+	filters := make([]any, 0, len(terms))
+	for _, term := range terms {
+		filter, err := MongoFilter(term)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return map[string]any{op: filters}, nil
+}
+
+func mongoMatch(n *matchNode) (map[string]any, error) {
+	v, err := mongoValue(n.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	pattern, ok := v.(string)
+	if !ok {
+		return nil, newSyntaxError(n.Keyword + " requires a string pattern")
+	}
+	switch n.Op {
+	case likeToken:
+		pattern = likeToRegexp(pattern)
+	case startsWithToken:
+		pattern = "^" + regexp.QuoteMeta(pattern)
+	case containsToken:
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	return mongoField(n.Lhs, "$regex", pattern)
+}
+
+// mongoField answers {path: {op: v}} for the field n.
+func mongoField(n AstNode, op string, v any) (map[string]any, error) {
+	path, err := mongoPath(n)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{path: map[string]any{op: v}}, nil
+}
+
+// mongoPath answers the field n in dot notation, where
+// array indexes are path segments, i.e. "tags.0".
+func mongoPath(n AstNode) (string, error) {
+	if u, ok := n.(*unaryNode); ok && u.Op == openToken {
+		return mongoPath(u.Child)
+	}
+	path, ok := FieldOf(n)
+	if !ok {
+		return "", newUnsupportedError("mongo filter requires a field, not " + mongoName(n))
+	}
+	segs := make([]string, len(path))
+	for i, seg := range path {
+		if seg.IsIndex {
+			segs[i] = strconv.Itoa(seg.Index)
+		} else {
+			segs[i] = seg.Name
+		}
+	}
+	return strings.Join(segs, "."), nil
+}
+
+// mongoValue answers the literal value of n. Fields on
+// the RHS are strings, as in formatting.
+func mongoValue(n AstNode) (any, error) {
+	switch t := n.(type) {
+	case *unaryNode:
+		if t.Op == openToken {
+			return mongoValue(t.Child)
+		}
+	case *valueNode:
+		return t.Value, nil
+	case *fieldNode:
+		return t.Path.String(), nil
+	case *paramNode:
+		if !t.Param.Bound {
+			return nil, newUnsupportedError("mongo filter on unbound placeholder " + t.Param.Text)
+		}
+		return t.Param.Value, nil
+	}
+	return nil, newUnsupportedError("mongo filter requires a value, not " + mongoName(n))
+}
+
+// mongoName describes n for errors.
+func mongoName(n AstNode) string {
+	switch t := n.(type) {
+	case *binaryNode:
+		return t.Keyword
+	case *unaryNode:
+		if t.Op == negToken {
+			return "negation"
+		}
+		return t.Keyword
+	case *callNode:
+		return "function " + t.Name
+	case *listNode:
+		return "list"
+	case *valueNode, *paramNode:
+		return "value"
+	}
+	return "expression"
+}
+
+var mongoOps = map[symbol]string{
+	assignToken: "$eq",
+	eqlToken:    "$eq",
+	neqToken:    "$ne",
+	ltToken:     "$lt",
+	lteToken:    "$lte",
+	gtToken:     "$gt",
+	gteToken:    "$gte",
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ------------------------------------------------------------
@@ -22,6 +23,7 @@ type nodeT struct {
 	// Lexing
 	Token *tokenT
 	Text  string
+	Pos   Position
 
 	// Parsing
 	Parent *nodeT `json:"-"`
@@ -57,14 +59,96 @@ func (n *nodeT) addChild(child *nodeT) {
 
 // asAst returns the AST node for this tree node.
 func (n *nodeT) asAst() (AstNode, error) {
+	ast, err := n.makeAst()
+	if err != nil {
+		return nil, errorAt(err, n)
+	}
+	return ast, nil
+}
+
+func (n *nodeT) makeAst() (AstNode, error) {
 	// fmt.Println("ast", n.Text)
 	switch n.Token.Symbol {
-	case eqlToken, neqToken, andToken, orToken, assignToken, listToken:
+	case eqlToken, neqToken, ltToken, lteToken, gtToken, gteToken, andToken, orToken, assignToken, listToken:
 		lhs, rhs, err := n.makeBinary()
 		if err != nil {
 			return nil, err
 		}
 		return &binaryNode{Op: n.Token.Symbol, Keyword: n.Token.Text, Lhs: lhs, Rhs: rhs}, nil
+	case inToken, notInToken:
+		if len(n.Children) != 2 {
+			return nil, newParseError("membership has wrong number of children: " + strconv.Itoa(len(n.Children)))
+		}
+		lhs, err := n.Children[0].asAst()
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := n.Children[1].makeList()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{Op: n.Token.Symbol, Keyword: n.Token.Text, Lhs: lhs, Rhs: rhs}, nil
+	case likeToken, startsWithToken, containsToken, matchesToken:
+		lhs, rhs, err := n.makeBinary()
+		if err != nil {
+			return nil, err
+		}
+		return newMatchNode(n.Token.Symbol, n.Token.Text, lhs, rhs)
+	case isNullToken, isNotNullToken:
+		field, err := n.makeUnary()
+		if err != nil {
+			return nil, err
+		}
+		return newPredicateNode(n.Token.Symbol, n.Token.Text, field)
+	case betweenToken:
+		return newBetweenNode(n)
+	case callToken:
+		args := make([]AstNode, 0, len(n.Children))
+		for _, child := range n.Children {
+			arg, err := child.asAst()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return newCallNode(n.Text, args, n.Pos)
+	case existsToken:
+		if len(n.Children) != 1 || n.Children[0].Token.Symbol != openToken {
+			return nil, newParseError("exists requires a parenthesized field")
+		}
+		field, err := n.Children[0].makeUnary()
+		if err != nil {
+			return nil, err
+		}
+		return newPredicateNode(existsToken, n.Token.Text, field)
+	case nullToken:
+		if len(n.Children) != 0 {
+			return nil, newParseError("null has wrong number of children: " + strconv.Itoa(len(n.Children)))
+		}
+		return &valueNode{Value: nil, Pos: n.Pos}, nil
+	case trueToken, falseToken:
+		if len(n.Children) != 0 {
+			return nil, newParseError("bool has wrong number of children: " + strconv.Itoa(len(n.Children)))
+		}
+		return &valueNode{Value: n.Token.Symbol == trueToken, Pos: n.Pos}, nil
+	case timeToken:
+		if len(n.Children) != 0 {
+			return nil, newParseError("time has wrong number of children: " + strconv.Itoa(len(n.Children)))
+		}
+		t, err := time.Parse(time.RFC3339Nano, n.Text)
+		if err != nil {
+			return nil, &Error{Code: SyntaxErrCode, Msg: "invalid timestamp " + strconv.Quote(n.Text), Err: err}
+		}
+		return &valueNode{Value: t, Pos: n.Pos}, nil
+	case durationToken:
+		if len(n.Children) != 0 {
+			return nil, newParseError("duration has wrong number of children: " + strconv.Itoa(len(n.Children)))
+		}
+		d, err := time.ParseDuration(n.Text)
+		if err != nil {
+			return nil, &Error{Code: SyntaxErrCode, Msg: "invalid duration " + strconv.Quote(n.Text), Err: err}
+		}
+		return &valueNode{Value: d, Pos: n.Pos}, nil
 	case floatToken:
 		if len(n.Children) != 0 {
 			return nil, newParseError("float has wrong number of children: " + strconv.Itoa(len(n.Children)))
@@ -73,7 +157,7 @@ func (n *nodeT) asAst() (AstNode, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &valueNode{Value: f64}, nil
+		return &valueNode{Value: f64, Pos: n.Pos}, nil
 	case intToken:
 		if len(n.Children) != 0 {
 			return nil, newParseError("int has wrong number of children: " + strconv.Itoa(len(n.Children)))
@@ -82,20 +166,58 @@ func (n *nodeT) asAst() (AstNode, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &valueNode{Value: i64}, nil
+		return &valueNode{Value: i64, Pos: n.Pos}, nil
 	case openToken:
 		child, err := n.makeUnary()
 		if err != nil {
 			return nil, err
 		}
 		return &unaryNode{Op: openToken, Child: child}, nil
+	case notToken:
+		child, err := n.makeUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{Op: notToken, Keyword: NotKeyword, Child: child}, nil
+	case negToken:
+		child, err := n.makeUnary()
+		if err != nil {
+			return nil, err
+		}
+		// Fold negative numbers directly into the value.
+		if v, ok := child.(*valueNode); ok {
+			switch t := v.Value.(type) {
+			case int64:
+				return &valueNode{Value: -t, Pos: n.Pos}, nil
+			case float64:
+				return &valueNode{Value: -t, Pos: n.Pos}, nil
+			}
+		}
+		return &unaryNode{Op: negToken, Child: child}, nil
+	case paramToken:
+		if len(n.Children) != 0 {
+			return nil, newParseError("param has wrong number of children: " + strconv.Itoa(len(n.Children)))
+		}
+		param, err := newParam(n.Text)
+		if err != nil {
+			return nil, err
+		}
+		return &paramNode{Param: param}, nil
 	case stringToken:
 		if len(n.Children) != 0 {
 			return nil, newParseError("string has wrong number of children: " + strconv.Itoa(len(n.Children)))
 		}
+		// Unquoted paths reference nested fields.
+		if !strings.HasPrefix(n.Text, `"`) && strings.ContainsAny(n.Text, ".[") {
+			path, err := ParseFieldPath(n.Text)
+			if err != nil {
+				return nil, err
+			}
+			return &fieldNode{Path: path, Pos: n.Pos}, nil
+		}
 		// Unwrap quoted text, which has served its purpose of allowing special characters.
 		text := strings.Trim(n.Text, `"`)
-		return &valueNode{Value: text}, nil
+		return &valueNode{Value: text, Pos: n.Pos}, nil
 	}
 	return nil, newParseError("on unknown token: " + strconv.Itoa(int(n.Token.Symbol)) + ", " + n.Token.Text)
 }
@@ -121,3 +243,21 @@ func (n *nodeT) makeUnary() (AstNode, error) {
 	}
 	return n.Children[0].asAst()
 }
+
+// makeList answers a list node from a parenthesized
+// chain of list tokens.
+func (n *nodeT) makeList() (AstNode, error) {
+	if n.Token.Symbol != openToken {
+		return nil, newParseError("list must be enclosed: " + n.Text)
+	}
+	child, err := n.makeUnary()
+	if err != nil {
+		return nil, err
+	}
+	list := &listNode{}
+	err = list.add(child)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
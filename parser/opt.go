@@ -1,5 +1,78 @@
 package parser
 
+import (
+	"math"
+)
+
+// ------------------------------------------------------------
+// PARSE-OPTIONS
+
+// ParseOptions contains options for parsing.
+type ParseOptions struct {
+	// Lenient restores the original, forgiving parsing behaviour:
+	// unknown operators are treated as strings and unconsumed
+	// tokens are discarded. Use only for backward compatibility,
+	// since it can silently change the meaning of an expression.
+	Lenient bool
+
+	// Limits on the size of an expression, to protect against
+	// hostile input. Zero uses the default and a negative value
+	// is unlimited. Each limit has its own error.
+
+	// MaxLength is the length of the expression in bytes (ErrTooLong).
+	MaxLength int
+	// MaxTokens is the number of tokens (ErrTooManyTokens).
+	MaxTokens int
+	// MaxDepth is the nesting depth of parentheses and
+	// operators (ErrTooDeep).
+	MaxDepth int
+	// MaxListItems is the number of items in a single list
+	// or function call (ErrTooManyItems).
+	MaxListItems int
+	// MaxFields is the number of distinct fields (ErrTooManyFields).
+	MaxFields int
+}
+
+// Default limits for ParseOptions.
+const (
+	DefaultMaxLength    = 64 * 1024
+	DefaultMaxTokens    = 10000
+	DefaultMaxDepth     = 100
+	DefaultMaxListItems = 1000
+	DefaultMaxFields    = 100
+)
+
+func (o ParseOptions) maxLength() int {
+	return limitOf(o.MaxLength, DefaultMaxLength)
+}
+
+func (o ParseOptions) maxTokens() int {
+	return limitOf(o.MaxTokens, DefaultMaxTokens)
+}
+
+func (o ParseOptions) maxDepth() int {
+	return limitOf(o.MaxDepth, DefaultMaxDepth)
+}
+
+func (o ParseOptions) maxListItems() int {
+	return limitOf(o.MaxListItems, DefaultMaxListItems)
+}
+
+func (o ParseOptions) maxFields() int {
+	return limitOf(o.MaxFields, DefaultMaxFields)
+}
+
+// limitOf answers the limit for the option v.
+func limitOf(v, def int) int {
+	switch {
+	case v == 0:
+		return def
+	case v < 0:
+		return math.MaxInt
+	}
+	return v
+}
+
 // ------------------------------------------------------------
 // OPT
 
@@ -0,0 +1,203 @@
+package parser
+
+import (
+	"strconv"
+)
+
+// ------------------------------------------------------------
+// PARAM
+
+// Param describes a placeholder in an expression. Placeholders
+// are positional (?), numbered ($1) or named (:name).
+type Param struct {
+	// Text is the placeholder as it appeared in the expression.
+	Text string
+	// Index is the 0-based argument index for positional and
+	// numbered placeholders, and -1 for named placeholders.
+	Index int
+	// Name is the name of a named placeholder.
+	Name string
+	// Value is the bound value, if Bound is true.
+	Value any
+	Bound bool
+}
+
+// NamedArg supplies the value for a named placeholder.
+type NamedArg struct {
+	Name  string
+	Value any
+}
+
+// ------------------------------------------------------------
+// PARAM-NODE
+
+// paramNode is a placeholder for a value supplied separately
+// from the expression.
+type paramNode struct {
+	Param Param
+}
+
+func (n *paramNode) Format(args FormatArgs) error {
+	s, err := formatParam(n.Param, args.Format)
+	if err != nil {
+		return err
+	}
+	_, err = args.Writer.WriteString(s)
+	return err
+}
+
+func (n *paramNode) Fields(args *FieldArgs) error {
+	return nil
+}
+
+func (n *paramNode) Extract(any) error {
+	return nil
+}
+
+func (n *paramNode) Eval(args EvalArgs) (any, error) {
+	if !n.Param.Bound {
+		return nil, newEvalError("unbound placeholder " + n.Param.Text)
+	}
+	return n.Param.Value, nil
+}
+
+// value answers my bound value, or the Param itself if I
+// haven't been bound, so clients can supply their own.
+func (n *paramNode) value() any {
+	if n.Param.Bound {
+		return n.Param.Value
+	}
+	return n.Param
+}
+
+// newParam answers a param from the placeholder text. Positional
+// params are indexed afterwards, once the whole AST is available.
+func newParam(text string) (Param, error) {
+	p := Param{Text: text, Index: -1}
+	switch {
+	case text == "?":
+	case len(text) > 1 && text[0] == '$':
+		i, err := strconv.Atoi(text[1:])
+		if err != nil || i < 1 {
+			return p, newSyntaxError("invalid placeholder " + text)
+		}
+		p.Index = i - 1
+	case len(text) > 1 && text[0] == ':':
+		p.Name = text[1:]
+	default:
+		return p, newSyntaxError("invalid placeholder " + text)
+	}
+	return p, nil
+}
+
+// ------------------------------------------------------------
+// BIND
+
+// Bind assigns values to the placeholders in the AST. args are
+// matched by index to positional and numbered placeholders;
+// named placeholders are matched to NamedArg args. If any
+// args are supplied then every placeholder must be bound, every
+// arg must be used, and positional and numbered placeholders
+// can't be mixed, since it's unclear which arg each would take.
+func Bind(ast AstNode, args ...any) error {
+	if len(args) < 1 {
+		return nil
+	}
+	if err := checkParamStyle(ast); err != nil {
+		return err
+	}
+	var positional []any
+	var names []string
+	named := make(map[string]any)
+	for _, arg := range args {
+		switch t := arg.(type) {
+		case NamedArg:
+			if _, ok := named[t.Name]; !ok {
+				names = append(names, t.Name)
+			}
+			named[t.Name] = t.Value
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	usedPositional := make([]bool, len(positional))
+	usedNamed := make(map[string]bool)
+	err := Walk(ast, func(n AstNode) error {
+		pn, ok := n.(*paramNode)
+		if !ok {
+			return nil
+		}
+		if pn.Param.Name != "" {
+			v, ok := named[pn.Param.Name]
+			if !ok {
+				return newBadRequestError("missing value for " + pn.Param.Text)
+			}
+			pn.Param.Value, pn.Param.Bound = v, true
+			usedNamed[pn.Param.Name] = true
+			return nil
+		}
+		if pn.Param.Index < 0 || pn.Param.Index >= len(positional) {
+			return newBadRequestError("missing value for " + pn.Param.Text)
+		}
+		pn.Param.Value, pn.Param.Bound = positional[pn.Param.Index], true
+		usedPositional[pn.Param.Index] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, used := range usedPositional {
+		if !used {
+			return newBadRequestError("unused argument " + strconv.Itoa(i+1))
+		}
+	}
+	for _, name := range names {
+		if !usedNamed[name] {
+			return newBadRequestError("unused argument :" + name)
+		}
+	}
+	return nil
+}
+
+// checkParamStyle answers an error if the AST has
+// both positional (?) and numbered ($1) placeholders.
+func checkParamStyle(ast AstNode) error {
+	var positional, numbered string
+	for _, p := range Params(ast) {
+		switch {
+		case p.Name != "":
+		case p.Text == "?":
+			positional = p.Text
+		default:
+			numbered = p.Text
+		}
+		if positional != "" && numbered != "" {
+			return newBadRequestError("placeholders mix " + positional + " and " + numbered)
+		}
+	}
+	return nil
+}
+
+// indexParams assigns the index of each positional placeholder.
+func indexParams(ast AstNode) error {
+	index := 0
+	return Walk(ast, func(n AstNode) error {
+		if pn, ok := n.(*paramNode); ok && pn.Param.Text == "?" {
+			pn.Param.Index = index
+			index++
+		}
+		return nil
+	})
+}
+
+// Params answers all placeholders in the AST, in order of appearance.
+func Params(ast AstNode) []Param {
+	var params []Param
+	Walk(ast, func(n AstNode) error {
+		if pn, ok := n.(*paramNode); ok {
+			params = append(params, pn.Param)
+		}
+		return nil
+	})
+	return params
+}
@@ -3,29 +3,86 @@ package parser
 import (
 	_ "fmt"
 	"strings"
+	"unicode/utf8"
 )
 
 // Parse converts an expression string into an AST.
 func Parse(term string) (AstNode, error) {
-	tokens, err := scan(term)
+	return ParseWith(term, ParseOptions{})
+}
+
+// ParseWith converts an expression string into an AST,
+// using the supplied options.
+func ParseWith(term string, opts ParseOptions) (AstNode, error) {
+	if max := opts.maxLength(); len(term) > max {
+		return nil, newLimitError(TooLongErrCode, max)
+	}
+	tokens, err := scan(term, opts)
 	if err != nil {
 		return nil, err
 	}
-	p := newParser(tokens)
+	p := newParser(tokens, endPosition(term), opts)
 	tree, err := p.Expression(0)
 	if err != nil {
 		return nil, err
 	}
 	if tree == nil {
-		return nil, newParseError("no result")
+		return nil, errorAt(newParseError("no result"), p.Peek())
+	}
+	// Every token must be consumed, otherwise part of the
+	// expression would be silently discarded.
+	if next := p.Peek(); next.Token.Symbol != illegalToken && !opts.Lenient {
+		return nil, errorAt(newSyntaxError("unexpected "+next.Text), next)
 	}
 	ast, err := tree.asAst()
 	if err != nil {
 		return nil, err
 	}
+	err = checkLimits(ast, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = indexParams(ast)
+	if err != nil {
+		return nil, err
+	}
 	return ast, nil
 }
 
+// checkLimits answers an error if ast exceeds the limits
+// that can only be checked on the finished tree.
+func checkLimits(ast AstNode, opts ParseOptions) error {
+	maxItems := opts.maxListItems()
+	err := Walk(ast, func(n AstNode) error {
+		items := 0
+		switch t := n.(type) {
+		case *listNode:
+			items = len(t.Items)
+		case *callNode:
+			items = len(t.Args)
+		}
+		if items > maxItems {
+			return newLimitError(TooManyItemsErrCode, maxItems)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fa := &FieldArgs{}
+	if err = ast.Fields(fa); err != nil {
+		return err
+	}
+	distinct := make(map[string]struct{}, len(fa.Fields))
+	for _, ref := range fa.Refs {
+		distinct[ref.Name] = struct{}{}
+		if max := opts.maxFields(); len(distinct) > max {
+			return errorAtPos(newLimitError(TooManyFieldsErrCode, max), ref.Pos, ref.Name)
+		}
+	}
+	return nil
+}
+
 func validate(term string, f Format) (string, error) {
 	ast, err := Parse(term)
 	if err != nil {
@@ -60,11 +117,17 @@ type parserT struct {
 	tokens   []*nodeT
 	position int
 	illegal  *nodeT
+	opts     ParseOptions
+	// depth is the current nesting of Expression.
+	depth    int
+	maxDepth int
 }
 
-func newParser(tokens []*nodeT) parser {
-	illegal := &nodeT{Token: tokenMap[illegalToken]}
-	return &parserT{tokens: tokens, position: 0, illegal: illegal}
+// newParser answers a parser for the tokens. end is the
+// position of the end of input, used for error reporting.
+func newParser(tokens []*nodeT, end Position, opts ParseOptions) parser {
+	illegal := &nodeT{Token: tokenMap[illegalToken], Pos: end}
+	return &parserT{tokens: tokens, position: 0, illegal: illegal, opts: opts, maxDepth: opts.maxDepth()}
 }
 
 func (p *parserT) Next() (*nodeT, error) {
@@ -89,13 +152,20 @@ func (p *parserT) Expression(rbp int) (*nodeT, error) {
 		return nil, err
 	}
 	if n == nil {
-		return nil, newParseError("premature stop")
+		return nil, errorAt(newParseError("premature stop"), p.illegal)
+	}
+	// Every level of nesting passes through here, so this
+	// bounds the recursion of the parser.
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > p.maxDepth {
+		return nil, errorAt(newLimitError(TooDeepErrCode, p.maxDepth), n)
 	}
 	//	fmt.Println("Expression on rbp", rbp, "next \"", n.Text, "\"", n.Token)
 	left, err := n.Token.nud(n, p)
 	//	fmt.Println("\tat", n.Text, "left", left, "err", err)
 	if err != nil {
-		return nil, err
+		return nil, errorAt(err, n)
 	}
 	//	fmt.Println("rbp binding", rbp, "peek binding", p.Peek().Token.BindingPower, "token", p.Peek().Token.Text, p.Peek().Token.Symbol)
 
@@ -106,13 +176,23 @@ func (p *parserT) Expression(rbp int) (*nodeT, error) {
 			return nil, err
 		}
 		if n == nil {
-			return nil, newParseError("premature stop")
+			return nil, errorAt(newParseError("premature stop"), p.illegal)
 		}
 		left, err = n.Token.led(n, p, left)
 		if err != nil {
-			return nil, err
+			return nil, errorAt(err, n)
 		}
 	}
 	//	fmt.Println("returning left", left.Text, left.Token.Text)
 	return left, nil
 }
+
+// endPosition answers the position just past the end of term.
+func endPosition(term string) Position {
+	start := strings.LastIndex(term, "\n") + 1
+	return Position{
+		Offset: len(term),
+		Line:   1 + strings.Count(term, "\n"),
+		Column: 1 + utf8.RuneCountInString(term[start:]),
+	}
+}
@@ -1,8 +1,14 @@
 package parser
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 // ---------------------------------------------------------
@@ -54,21 +60,1633 @@ func TestValidate(t *testing.T) {
 		{"id = 10 OR step = 1", "id = 10 OR step = 1", nil},
 		{"id = 10 or step = 1", "id = 10 OR step = 1", nil},
 		{"id = 10 AND form = \"wd-20\"", "id = 10 AND form = wd-20", nil},
-		{"id = 10 and form = wd-20", "", syntaxErr},
-		{"id = 10 && form = wd20", "form = wd20", nil}, // XXX This should produce an error but not sure how to identify that
+		{"id = 10 and form = wd-20", "", ErrSyntax},
+		{"id = 10 && form = wd20", "", ErrSyntax},
+		{"id = 10)", "", ErrSyntax},
+		{"id = 10 (form = 2)", "", ErrSyntax},
+		{"id = 10 form", "", ErrSyntax},
+		{"id = 10 ]", "", ErrSyntax},
 		{"id, form", "id, form", nil},
 		{"id,  form", "id, form", nil},
 		{"id, form, type", "id, form, type", nil},
+		{`address.city = "Paris"`, "address.city = Paris", nil},
+		{`tags[0] = "x" AND a.b[2].c > 1`, "tags[0] = x AND a.b[2].c > 1", nil},
+		{`a..b = 1`, "", ErrSyntax},
+		{`a. = 1`, "", ErrSyntax},
+		{`a[x] = 1`, "", ErrSyntax},
+		{`a[0]b = 1`, "", ErrSyntax},
+		{`name LIKE "smi%"`, "name LIKE smi%", nil},
+		{`name startswith "smi" AND tags contains "x"`, "name STARTSWITH smi AND tags CONTAINS x", nil},
+		{`name MATCHES "^s.*h$"`, "name MATCHES ^s.*h$", nil},
+		{`name MATCHES "("`, "", ErrSyntax},
+		{`name LIKE 5`, "", ErrSyntax},
+		{`name LIKE ?`, "name LIKE ?", nil},
+		{"age >= 21 AND score < 100", "age >= 21 AND score < 100", nil},
+		{"age>=21", "age >= 21", nil},
+		{"age<=21 or age>65", "age <= 21 OR age > 65", nil},
+		{"id == 10", "id == 10", nil},
+		{"id != 10", "id != 10", nil},
+		{`NOT (status = "archived")`, "NOT (status = archived)", nil},
+		{`not status = "archived"`, "(NOT status) = archived", nil},
+		{"NOT a = 1 AND b = 2", "(NOT a) = 1 AND b = 2", nil},
+		{"id = -5", "id = -5", nil},
+		{"id = -2.5", "id = -2.5", nil},
+		{"id > -5 AND id < 5", "id > -5 AND id < 5", nil},
+		{"id = -count", "id = -count", nil},
+		{"id = 5 -", "", ErrSyntax},
+		{"NOT", "", ErrParse},
+		{`region IN ("us", "eu", "apac")`, "region IN (us, eu, apac)", nil},
+		{`region in ("us")`, "region IN (us)", nil},
+		{`region NOT IN (1, 2) AND id = 3`, "region NOT IN (1, 2) AND id = 3", nil},
+		{`region IN "us"`, "", ErrSyntax},
+		{`region NOT = 1`, "", ErrSyntax},
+		{"id = ? AND name = ?", "id = ? AND name = ?", nil},
+		{"id = $1 AND name = :name", "id = $1 AND name = :name", nil},
+		{"id IN (?, ?)", "id IN (?, ?)", nil},
+		{"id = $", "", ErrSyntax},
+		{"id = $a", "", ErrSyntax},
+		{"id = :", "", ErrSyntax},
+		{"email IS NULL", "email IS NULL", nil},
+		{"email is not null AND a.b IS NULL", "email IS NOT NULL AND a.b IS NULL", nil},
+		{"exists(email) OR NOT EXISTS(a.b)", "EXISTS(email) OR NOT EXISTS(a.b)", nil},
+		{"email = null", "email = NULL", nil},
+		{"email IS 5", "", ErrSyntax},
+		{"email IS NOT", "", ErrSyntax},
+		{"email IS NULL IS NULL", "", ErrSyntax},
+		{"EXISTS email", "", ErrSyntax},
+		{"EXISTS(5)", "", ErrSyntax},
+		{"active = true OR active = FALSE", "active = true OR active = false", nil},
+		{`at > t"2026-01-02T03:04:05Z"`, `at > t"2026-01-02T03:04:05Z"`, nil},
+		{`at < t"2026-01-02T03:04:05.5+02:00"`, `at < t"2026-01-02T03:04:05.5+02:00"`, nil},
+		{`ttl >= d"1h30m"`, `ttl >= d"1h30m0s"`, nil},
+		{`t = "x" AND d = 1`, "t = x AND d = 1", nil},
+		{`at > t"2026-01-02"`, "", ErrSyntax},
+		{`ttl > d"soon"`, "", ErrSyntax},
+		{"id BETWEEN 1 AND 10", "id BETWEEN 1 AND 10", nil},
+		{"id between -1 and ? AND b = 2", "id BETWEEN -1 AND ? AND b = 2", nil},
+		{"id BETWEEN [1, 10]", "id BETWEEN 1 AND 10", nil},
+		{"id BETWEEN [1, 10)", "(id >= 1 AND id < 10)", nil},
+		{"NOT (a.b BETWEEN (x, y])", "NOT ((a.b > x AND a.b <= y))", nil},
+		{"tags[0] BETWEEN [a, b]", "tags[0] BETWEEN a AND b", nil},
+		{"id BETWEEN 1", "", ErrSyntax},
+		{"id BETWEEN 1 OR 2", "", ErrSyntax},
+		{"id BETWEEN [1 10]", "", ErrSyntax},
+		{"id BETWEEN [1, 10", "", ErrSyntax},
+		{"id BETWEEN (1) AND 5", "id BETWEEN (1) AND 5", nil},
+		{"id BETWEEN (abs(-1)) AND (5)", "id BETWEEN (abs(-1)) AND (5)", nil},
+		{"id BETWEEN (abs(-1), 5]", "(id > abs(-1) AND id <= 5)", nil},
+		{"id BETWEEN (1, 10", "", ErrSyntax},
+		{"5 BETWEEN 1 AND 10", "", ErrSyntax},
+		{"id BETWEEN 1 AND 10 BETWEEN 2 AND 3", "", ErrSyntax},
+		{`lower(name) = "bob"`, "lower(name) = bob", nil},
+		{"LEN(tags) > 2 AND at < now()", "len(tags) > 2 AND at < now()", nil},
+		{"abs(len(a.b)) = upper(x)", "abs(len(a.b)) = upper(x)", nil},
+		{"a = 1 AND (b = 2)", "a = 1 AND (b = 2)", nil},
+		{"lower (name) = 1", "", ErrSyntax},
+		{"nope(name) = 1", "", ErrSyntax},
+		{"lower(a, b) = 1", "", ErrSyntax},
+		{"now(1) = 1", "", ErrSyntax},
+		{"lower(5) = 1", "", ErrMismatch},
+		{"abs(lower(a)) = 1", "", ErrMismatch},
+		{"lower(a = 1", "", ErrSyntax},
 	}
 	for i, v := range table {
 		have, haveErr := validate(v.term, nil)
 		//		panic(nil)
 		if v.wantErr == nil && haveErr != nil {
 			t.Fatalf("TestValidate %v expected no error but has %v", i, haveErr)
-		} else if v.wantErr != nil && haveErr == nil {
-			t.Fatalf("TestValidate %v has no error but exptected %v", i, v.wantErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestValidate %v has error %v but exptected %v", i, haveErr, v.wantErr)
 		} else if have != v.want {
 			t.Fatalf("TestValidate %v has \"%v\" but wanted \"%v\"", i, have, v.want)
 		}
 	}
 }
+
+// ---------------------------------------------------------
+// TEST-EXTRACT
+func TestExtract(t *testing.T) {
+	table := []struct {
+		term    string
+		want    string
+		wantErr error
+	}{
+		{"id = 10", "id=10", nil},
+		{"id = 10 AND age >= 21", "AND id=10 age>=21", nil},
+		{"age < 21 OR age != 30", "OR age<21 age!=30", nil},
+		{`(id = 1) AND region IN ("us", 2)`, "AND id=1 region IN[us 2]", nil},
+		{`region NOT IN (1.5)`, "region NOT IN[1.5]", nil},
+		{`a.b LIKE "x%" OR c CONTAINS "y"`, "OR a.b LIKE x% c CONTAINS y", nil},
+		{`a IS NULL AND EXISTS(b.c)`, "AND a IS NULL b.c EXISTS", nil},
+		{`a BETWEEN 1 AND 2 OR b BETWEEN (x, y]`, "OR a [1 2] b (x y]", nil},
+		{`x = 1 AND NOT (y = 2 OR z)`, "AND x=1 NOT OR y=2", nil},
+		{`x = 1 AND -y`, "AND x=1", ErrUnsupported},
+		{`lower(name) = "bob" OR a = 1`, "OR a=1", ErrUnsupported},
+		{`a = 1 AND b < len(c)`, "AND a=1", ErrUnsupported},
+	}
+	for i, v := range table {
+		ast, haveErr := Parse(v.term)
+		ex := &testExtractor{}
+		if haveErr == nil {
+			haveErr = ast.Extract(ex)
+		}
+		have := strings.Join(ex.parts, " ")
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestExtract %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && haveErr == nil {
+			t.Fatalf("TestExtract %v has no error but exptected %v", i, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestExtract %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+
+	// Extractors only receive the interfaces they implement.
+	ast, _ := Parse("id = 10 AND age >= 21")
+	ex := &binaryExtractor{}
+	if err := ast.Extract(ex); err != nil {
+		t.Fatalf("TestExtract binary has error %v", err)
+	} else if have := strings.Join(ex.parts, " "); have != "AND id=10" {
+		t.Fatalf("TestExtract binary has \"%v\"", have)
+	}
+	// A NOT can't be dropped without widening the condition.
+	ast, _ = Parse("x = 1 AND NOT (y = 2)")
+	if err := ast.Extract(&binaryExtractor{}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("TestExtract binary NOT has %v but wanted ErrUnsupported", err)
+	}
+}
+
+// binaryExtractor implements only ExtractBinary.
+type binaryExtractor struct {
+	parts []string
+}
+
+func (e *binaryExtractor) BinaryConjunction(keyword string) error {
+	e.parts = append(e.parts, keyword)
+	return nil
+}
+
+func (e *binaryExtractor) BinaryAssignment(lhs string, rhs any) error {
+	e.parts = append(e.parts, fmt.Sprintf("%v=%v", lhs, rhs))
+	return nil
+}
+
+// testExtractor records every extraction as a string.
+type testExtractor struct {
+	parts []string
+}
+
+func (e *testExtractor) BinaryConjunction(keyword string) error {
+	e.parts = append(e.parts, keyword)
+	return nil
+}
+
+func (e *testExtractor) BinaryAssignment(lhs string, rhs any) error {
+	e.parts = append(e.parts, fmt.Sprintf("%v=%v", lhs, rhs))
+	return nil
+}
+
+func (e *testExtractor) BinaryComparison(lhs string, keyword string, rhs any) error {
+	e.parts = append(e.parts, fmt.Sprintf("%v%v%v", lhs, keyword, rhs))
+	return nil
+}
+
+func (e *testExtractor) BinaryIn(lhs string, keyword string, rhs []any) error {
+	e.parts = append(e.parts, fmt.Sprintf("%v %v%v", lhs, keyword, rhs))
+	return nil
+}
+
+func (e *testExtractor) UnaryNot() error {
+	e.parts = append(e.parts, NotKeyword)
+	return nil
+}
+
+// ---------------------------------------------------------
+// TEST-BIND
+func TestBind(t *testing.T) {
+	table := []struct {
+		term    string
+		args    []any
+		want    string
+		wantErr error
+	}{
+		{"id = ?", []any{10}, "id = 10", nil},
+		{"id = ? AND name = ?", []any{10, "a"}, "id = 10 AND name = a", nil},
+		{"id = $2 AND name = $1", []any{"a", 10}, "id = 10 AND name = a", nil},
+		{"id = :id", []any{NamedArg{Name: "id", Value: 10}}, "id = 10", nil},
+		{"id IN (?, :b)", []any{1, NamedArg{Name: "b", Value: 2}}, "id IN (1, 2)", nil},
+		{"id = ?", nil, "id = ?", nil},
+		{"id = ? AND name = ?", []any{10}, "", ErrBadRequest},
+		{"id = :id", []any{10}, "", ErrBadRequest},
+		{"id = $1 OR name = $1", []any{10}, "id = 10 OR name = 10", nil},
+		{"id = ?", []any{10, 11}, "", ErrBadRequest},
+		{"id = $2", []any{10, 11}, "", ErrBadRequest},
+		{"id = :id", []any{NamedArg{Name: "id", Value: 10}, NamedArg{Name: "other", Value: 11}}, "", ErrBadRequest},
+		{"id = ? AND name = $2", []any{10, 11}, "", ErrBadRequest},
+	}
+	for i, v := range table {
+		ast, haveErr := Parse(v.term)
+		have := ""
+		if haveErr == nil {
+			haveErr = Bind(ast, v.args...)
+		}
+		if haveErr == nil {
+			var sb strings.Builder
+			args := FormatArgs{Writer: &sb, Format: _defaultFormat}
+			haveErr = ast.Format(args)
+			have = sb.String()
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestBind %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestBind %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestBind %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+
+	// A ParamFormat renders placeholders natively.
+	ast, _ := Parse("id = ? AND name = ?")
+	var sb strings.Builder
+	ast.Format(FormatArgs{Writer: &sb, Format: atParamFormat{_defaultFormat}})
+	if have := sb.String(); have != "id = @p1 AND name = @p2" {
+		t.Fatalf("TestBind ParamFormat has \"%v\"", have)
+	}
+}
+
+// atParamFormat renders placeholders as @pN.
+type atParamFormat struct {
+	Format
+}
+
+func (f atParamFormat) Param(p Param) (string, error) {
+	return "@p" + strconv.Itoa(p.Index+1), nil
+}
+
+// ---------------------------------------------------------
+// TEST-EVAL
+func TestEval(t *testing.T) {
+	type person struct {
+		Name   string
+		Age    int
+		Score  float64
+		Active bool
+	}
+	bob := &person{Name: "bob", Age: 30, Score: 9.5, Active: true}
+	m := map[string]any{"name": "sue", "age": 20}
+	nested := map[string]any{
+		"Address": struct{ City string }{City: "Paris"},
+		"tags":    []string{"a", "b"},
+	}
+	type place struct{ City string }
+	type located struct {
+		*place
+		Name string
+	}
+	withNull := map[string]any{"email": nil}
+	unsigned := map[string]any{"small": uint64(7), "big": uint64(math.MaxUint64)}
+	timed := map[string]any{
+		"at":  time.Date(2026, 1, 2, 1, 0, 0, 0, time.UTC),
+		"ttl": 90 * time.Minute,
+	}
+	strict := Opt{Strict: true}
+
+	table := []struct {
+		term    string
+		item    any
+		opt     Opt
+		want    any
+		wantErr error
+	}{
+		{`Name = "bob"`, bob, Opt{}, true, nil},
+		{`name = bob`, bob, Opt{}, false, nil},
+		{`name = bob`, bob, strict, nil, ErrEval},
+		{`Age >= 21 AND Score < 10`, bob, Opt{}, true, nil},
+		{`Age < 21 OR Name != "bob"`, bob, Opt{}, false, nil},
+		{`NOT (Age = 30)`, bob, Opt{}, false, nil},
+		{`Age > -1`, bob, Opt{}, true, nil},
+		{`Name IN ("sue", "bob")`, bob, Opt{}, true, nil},
+		{`Name NOT IN ("sue", "bob")`, bob, Opt{}, false, nil},
+		{`Active`, bob, Opt{}, true, nil},
+		{`Age`, bob, Opt{}, 30, nil},
+		{`Name, Age`, bob, Opt{}, []any{"bob", 30}, nil},
+		{`name = "sue" AND age = 20`, m, Opt{}, true, nil},
+		{`age = 20.0`, m, Opt{}, true, nil},
+		{`Name = 10`, bob, Opt{}, false, nil},
+		{`Address.City = "Paris" AND tags[1] = "b"`, nested, Opt{}, true, nil},
+		{`Address.Zip = 1`, nested, strict, nil, ErrEval},
+		{`Name LIKE "b_b" AND Name LIKE "%o%" AND NOT (Name LIKE "o%")`, bob, Opt{}, true, nil},
+		{`Name LIKE "b.b"`, bob, Opt{}, false, nil},
+		{`Name STARTSWITH "bo" AND Name CONTAINS "ob"`, bob, Opt{}, true, nil},
+		{`Name MATCHES "^b.b$"`, bob, Opt{}, true, nil},
+		{`tags CONTAINS "b"`, nested, Opt{}, true, nil},
+		{`Age LIKE "3%"`, bob, Opt{}, false, nil},
+		{`Age LIKE "3%"`, bob, strict, nil, ErrMismatch},
+		{`tags[5] = "b"`, nested, strict, nil, ErrEval},
+		{`Name != 10`, bob, Opt{}, true, nil},
+		{`Name = 10`, bob, strict, nil, ErrMismatch},
+		{`Missing = 10`, bob, Opt{}, false, nil},
+		{`Missing = 10`, bob, strict, nil, ErrEval},
+		{`Name AND Age`, bob, strict, nil, ErrMismatch},
+		{`Age = ?`, bob, Opt{}, nil, ErrEval},
+		{`email IS NULL`, withNull, strict, true, nil},
+		{`email IS NOT NULL`, withNull, strict, false, nil},
+		{`EXISTS(email) AND NOT EXISTS(phone)`, withNull, strict, true, nil},
+		{`phone IS NULL OR phone IS NOT NULL`, withNull, strict, false, nil},
+		{`Name IS NOT NULL AND NOT EXISTS(Address.City)`, bob, strict, true, nil},
+		{`Address.City IS NULL`, bob, strict, false, nil},
+		{`email = NULL`, withNull, Opt{}, true, nil},
+		{`Active = true AND NOT Active = false`, bob, strict, true, nil},
+		{`at > t"2026-01-01T00:00:00Z" AND at < t"2026-01-02T03:04:05+01:00"`, timed, strict, true, nil},
+		{`ttl > d"1h" AND ttl <= d"90m"`, timed, strict, true, nil},
+		{`ttl > 1`, timed, strict, nil, ErrMismatch},
+		{`Age BETWEEN 30 AND 40 AND Age BETWEEN [20, 30]`, bob, strict, true, nil},
+		{`Age BETWEEN (30, 40] OR Age BETWEEN [20, 30)`, bob, strict, false, nil},
+		{`at BETWEEN [t"2026-01-02T00:00:00Z", t"2026-01-03T00:00:00Z")`, timed, strict, true, nil},
+		{`Name BETWEEN 1 AND 2`, bob, Opt{}, false, nil},
+		{`Name BETWEEN 1 AND 2`, bob, strict, nil, ErrMismatch},
+		{`upper(Name) = "BOB" AND len(Name) = 3`, bob, strict, true, nil},
+		{`abs(-2.5) = 2.5 AND at < now()`, timed, strict, true, nil},
+		{`len(tags) = 2 AND abs(-2) = 2`, nested, strict, true, nil},
+		{`lower(Age) = "30"`, bob, Opt{}, false, nil},
+		{`lower(Age) = "30"`, bob, strict, nil, ErrMismatch},
+		{`small = 7 AND -small = -7`, unsigned, strict, true, nil},
+		{`City = "Paris"`, located{place: &place{City: "Paris"}}, strict, true, nil},
+		{`City = "Paris"`, located{Name: "bob"}, Opt{}, false, nil},
+		{`City = "Paris"`, located{Name: "bob"}, strict, nil, ErrEval},
+		{`big > 1`, unsigned, Opt{}, nil, ErrMismatch},
+		{`-big < 0`, unsigned, Opt{}, nil, ErrMismatch},
+	}
+	for i, v := range table {
+		ast, haveErr := Parse(v.term)
+		var have any
+		if haveErr == nil {
+			have, haveErr = Eval(ast, v.item, v.opt)
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestEval %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && haveErr == nil {
+			t.Fatalf("TestEval %v has no error but exptected %v", i, v.wantErr)
+		} else if fmt.Sprintf("%v", have) != fmt.Sprintf("%v", v.want) && v.wantErr == nil {
+			t.Fatalf("TestEval %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-EVAL-TYPED
+func TestEvalTyped(t *testing.T) {
+	item := map[string]any{"name": "sue", "age": 20, "score": 1.5}
+	ast, err := Parse("age")
+	if err != nil {
+		t.Fatalf("TestEvalTyped parse error %v", err)
+	}
+	if have, err := EvalInt(ast, item, Opt{}); have != 20 || err != nil {
+		t.Fatalf("TestEvalTyped has %v %v but wanted 20", have, err)
+	}
+	if have, err := EvalFloat64(ast, item, Opt{}); have != 20 || err != nil {
+		t.Fatalf("TestEvalTyped has %v %v but wanted 20.0", have, err)
+	}
+	if have, err := EvalFloat64(ast, item, Opt{Strict: true, OnError: -1.0}); have != -1 || err == nil {
+		t.Fatalf("TestEvalTyped has %v %v but wanted OnError", have, err)
+	}
+	if have, err := EvalBool(ast, item, Opt{OnError: true}); have != true || err == nil {
+		t.Fatalf("TestEvalTyped has %v %v but wanted OnError", have, err)
+	}
+	if have, err := EvalString(ast, item, Opt{}); have != "20" || err != nil {
+		t.Fatalf("TestEvalTyped has %v %v but wanted \"20\"", have, err)
+	}
+	// Nodes from outside the package need not be Evaluators.
+	if _, err := Eval(opaqueNode{}, item, Opt{}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("TestEvalTyped has %v but wanted ErrUnsupported", err)
+	}
+}
+
+// opaqueNode is an AstNode that can't be evaluated.
+type opaqueNode struct{}
+
+func (opaqueNode) Format(args FormatArgs) error { return nil }
+func (opaqueNode) Fields(args *FieldArgs) error { return nil }
+func (opaqueNode) Extract(any) error            { return nil }
+
+// ---------------------------------------------------------
+// TEST-FIELDS
+func TestFields(t *testing.T) {
+	table := []struct {
+		term    string
+		want    string
+		wantErr error
+	}{
+		{"id = 10", "id@1:1", nil},
+		{"id = name", "id@1:1", nil},
+		{"id=10 AND (form >= 2)", "id@1:1 form@1:12", nil},
+		{"id IN (a, b) OR\n  NOT (x != 1)", "id@1:1 x@2:8", nil},
+		{"id, form", "id@1:1 form@1:5", nil},
+		{"a.b = 1 AND tags[0] IN (x)", "a.b@1:1 tags[0]@1:13", nil},
+	}
+	for i, v := range table {
+		ast, haveErr := Parse(v.term)
+		fa := &FieldArgs{}
+		if haveErr == nil {
+			haveErr = ast.Fields(fa)
+		}
+		var parts []string
+		for _, ref := range fa.Refs {
+			parts = append(parts, ref.Name+"@"+ref.Pos.String())
+		}
+		have := strings.Join(parts, " ")
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestFields %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && haveErr == nil {
+			t.Fatalf("TestFields %v has no error but exptected %v", i, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestFields %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-ERRORS
+func TestErrors(t *testing.T) {
+	table := []struct {
+		term    string
+		wantErr error
+		wantPos string
+		want    string
+	}{
+		{"id = 10 -", ErrSyntax, "1:9", "doc: invalid syntax (unexpected -) at 1:9\nid = 10 -\n        ^"},
+		{"id = \n\t(a", ErrParse, "2:4", "doc: parse (missing close for () at 2:4\n\t(a\n\t  ^"},
+		{"id IN 5", ErrSyntax, "1:4", ""},
+		{"id = $", ErrSyntax, "1:6", ""},
+		{`id = "abc`, ErrSyntax, "1:6", ""},
+		{"id =", ErrParse, "1:5", ""},
+		{"id = 'x'", ErrSyntax, "1:6", "doc: invalid syntax (unsupported quote ', use \") at 1:6\nid = 'x'\n     ^"},
+		{"id = 'abc'", ErrSyntax, "1:6", "doc: invalid syntax (unsupported quote ', use \") at 1:6\nid = 'abc'\n     ^"},
+		{"id = `x`", ErrSyntax, "1:6", ""},
+	}
+	for i, v := range table {
+		_, haveErr := Parse(v.term)
+		var e *Error
+		if !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestErrors %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if !errors.As(haveErr, &e) || e.Pos.String() != v.wantPos {
+			t.Fatalf("TestErrors %v has error %v but wanted position %v", i, haveErr, v.wantPos)
+		} else if have := FormatError(v.term, haveErr); v.want != "" && have != v.want {
+			t.Fatalf("TestErrors %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-LENIENT
+func TestLenient(t *testing.T) {
+	table := []struct {
+		term    string
+		want    string
+		wantErr error
+	}{
+		{"id = 10", "id = 10", nil},
+		{"id = 10 && form = wd20", "form = wd20", nil},
+		{"id = 10)", "id = 10", nil},
+		{"id = 10 form", "form", nil},
+		{"(id = 10", "", ErrParse},
+	}
+	for i, v := range table {
+		ast, haveErr := ParseWith(v.term, ParseOptions{Lenient: true})
+		have := ""
+		if haveErr == nil {
+			var sb strings.Builder
+			haveErr = ast.Format(FormatArgs{Writer: &sb, Format: _defaultFormat})
+			have = sb.String()
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestLenient %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestLenient %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestLenient %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-PRECEDENCE
+func TestPrecedence(t *testing.T) {
+	table := []struct {
+		term    string
+		want    string
+		wantErr error
+	}{
+		{"a = 1 OR b = 2 AND c = 3", "(OR (= a 1) (AND (= b 2) (= c 3)))", nil},
+		{"a = 1 AND b = 2 OR c = 3", "(OR (AND (= a 1) (= b 2)) (= c 3))", nil},
+		{"a OR b OR c", "(OR (OR a b) c)", nil},
+		{"NOT a = 1 AND b = 2", "(AND (= (NOT a) 1) (= b 2))", nil},
+		{"NOT (a = 1) AND b = 2", "(AND (NOT (( (= a 1))) (= b 2))", nil},
+		{"NOT NOT a", "(NOT (NOT a))", nil},
+		{"a, b OR c", "(, a (OR b c))", nil},
+		{"a = -b", "(= a (- b))", nil},
+		{"(a = 1 OR b = 2) AND c = 3", "(AND (( (OR (= a 1) (= b 2))) (= c 3))", nil},
+		{"a = b = c", "", ErrSyntax},
+		{"a < b >= c", "", ErrSyntax},
+		{"a = 1 NOT IN (2)", "", ErrSyntax},
+		{"NOT (a IS NULL) AND EXISTS(b)", "(AND (NOT (( (IS NULL a))) (EXISTS b))", nil},
+		{"NOT a IS NULL", "", ErrSyntax},
+		{"a BETWEEN 1 AND 2 AND b", "(AND (BETWEEN a 1 2) b)", nil},
+		{"len(a, ) = 1", "", ErrSyntax},
+		{"-len(a) = lower(b)", "(= (- (len() a)) (lower() b))", nil},
+	}
+	for i, v := range table {
+		ast, haveErr := Parse(v.term)
+		have := ""
+		if haveErr == nil {
+			have = treeString(ast)
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestPrecedence %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestPrecedence %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestPrecedence %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-FORMAT-PARENS
+func TestFormatParens(t *testing.T) {
+	v := func(a any) AstNode { return &valueNode{Value: a} }
+	eq := func(l, r AstNode) AstNode {
+		return &binaryNode{Op: assignToken, Keyword: AssignKeyword, Lhs: l, Rhs: r}
+	}
+	and := func(l, r AstNode) AstNode { return &binaryNode{Op: andToken, Keyword: AndKeyword, Lhs: l, Rhs: r} }
+	or := func(l, r AstNode) AstNode { return &binaryNode{Op: orToken, Keyword: OrKeyword, Lhs: l, Rhs: r} }
+	not := func(c AstNode) AstNode { return &unaryNode{Op: notToken, Keyword: NotKeyword, Child: c} }
+
+	table := []struct {
+		ast  AstNode
+		want string
+	}{
+		{and(eq(v("a"), v(1)), eq(v("b"), v(2))), "a = 1 AND b = 2"},
+		{and(or(v("a"), v("b")), v("c")), "(a OR b) AND c"},
+		{or(v("a"), and(v("b"), v("c"))), "a OR b AND c"},
+		{or(v("a"), or(v("b"), v("c"))), "a OR (b OR c)"},
+		{or(or(v("a"), v("b")), v("c")), "a OR b OR c"},
+		{not(and(v("a"), v("b"))), "NOT (a AND b)"},
+		{not(eq(v("a"), v(1))), "NOT (a = 1)"},
+		{eq(not(v("a")), v(1)), "(NOT a) = 1"},
+	}
+	for i, v := range table {
+		var sb strings.Builder
+		haveErr := v.ast.Format(FormatArgs{Writer: &sb, Format: _defaultFormat})
+		have := sb.String()
+		if haveErr != nil {
+			t.Fatalf("TestFormatParens %v expected no error but has %v", i, haveErr)
+		} else if have != v.want {
+			t.Fatalf("TestFormatParens %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// treeString answers the AST as an s-expression.
+func treeString(n AstNode) string {
+	switch t := n.(type) {
+	case *binaryNode:
+		return "(" + t.Keyword + " " + treeString(t.Lhs) + " " + treeString(t.Rhs) + ")"
+	case *unaryNode:
+		op := t.Keyword
+		switch t.Op {
+		case openToken:
+			op = "("
+		case negToken:
+			op = "-"
+		}
+		return "(" + op + " " + treeString(t.Child) + ")"
+	case *listNode:
+		parts := make([]string, 0, len(t.Items))
+		for _, item := range t.Items {
+			parts = append(parts, treeString(item))
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	case *valueNode:
+		return fmt.Sprintf("%v", t.Value)
+	case *paramNode:
+		return t.Param.Text
+	case *fieldNode:
+		return t.Path.String()
+	case *matchNode:
+		return "(" + t.Keyword + " " + treeString(t.Lhs) + " " + treeString(t.Pattern) + ")"
+	case *predicateNode:
+		return "(" + t.Keyword + " " + treeString(t.Field) + ")"
+	case *callNode:
+		parts := make([]string, 0, len(t.Args)+1)
+		parts = append(parts, t.Name+"()")
+		for _, arg := range t.Args {
+			parts = append(parts, treeString(arg))
+		}
+		return "(" + strings.Join(parts, " ") + ")"
+	case *betweenNode:
+		return "(" + t.Keyword + " " + treeString(t.Field) + " " + treeString(t.Lower) + " " + treeString(t.Upper) + ")"
+	}
+	return fmt.Sprintf("%T", n)
+}
+
+// ---------------------------------------------------------
+// TEST-FIELD-PATH
+func TestFieldPath(t *testing.T) {
+	table := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{"a", "a|a|/a|a", nil},
+		{"address.city", "address.city|address.city|/address/city|address->>'city'", nil},
+		{"doc.tags[0]", "doc.tags[0]|doc.tags.0|/doc/tags/0|doc->'tags'->>0", nil},
+		{"a[1][2].b/c", "a[1][2].b/c|a.1.2.b/c|/a/1/2/b~1c|a->1->2->>'b/c'", nil},
+		{"[0]", "", ErrSyntax},
+		{"a.", "", ErrSyntax},
+		{"a[", "", ErrSyntax},
+		{"a[-1]", "", ErrSyntax},
+	}
+	for i, v := range table {
+		p, haveErr := ParseFieldPath(v.path)
+		have := ""
+		if haveErr == nil {
+			have = strings.Join([]string{p.String(), p.DotString(), p.Pointer(), p.JSONB()}, "|")
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestFieldPath %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestFieldPath %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestFieldPath %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+func (e *testExtractor) BinaryMatch(lhs string, keyword string, pattern any) error {
+	e.parts = append(e.parts, fmt.Sprintf("%v %v %v", lhs, keyword, pattern))
+	return nil
+}
+
+func (e *testExtractor) FieldBetween(lhs string, r Range) error {
+	lb, ub := "[", "]"
+	if r.LowerExclusive {
+		lb = "("
+	}
+	if r.UpperExclusive {
+		ub = ")"
+	}
+	e.parts = append(e.parts, fmt.Sprintf("%v %v%v %v%v", lhs, lb, r.Lower, r.Upper, ub))
+	return nil
+}
+
+func (e *testExtractor) FieldPredicate(lhs string, keyword string) error {
+	e.parts = append(e.parts, fmt.Sprintf("%v %v", lhs, keyword))
+	return nil
+}
+
+// ---------------------------------------------------------
+// TEST-UNSUPPORTED
+func TestUnsupported(t *testing.T) {
+	ast, err := Parse(`name MATCHES "x" AND id = 1`)
+	if err != nil {
+		t.Fatalf("TestUnsupported parse error %v", err)
+	}
+	f := &_format{keywords: map[string]string{AndKeyword: " && ", AssignKeyword: " == "}}
+	var sb strings.Builder
+	err = ast.Format(FormatArgs{Writer: &sb, Format: f})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("TestUnsupported has error %v but wanted %v", err, ErrUnsupported)
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-CALL
+func TestCall(t *testing.T) {
+	RegisterFunc(Func{Name: "testDriverOnly", Args: []ArgType{StringArg, NumberArg}, Variadic: true, Result: BoolArg})
+	t.Cleanup(func() {
+		funcsMu.Lock()
+		defer funcsMu.Unlock()
+		delete(funcs, "testdriveronly")
+	})
+	f := &_format{keywords: map[string]string{AssignKeyword: " = ", ListKeyword: ", ", AndKeyword: " AND ", "lower": "LCASE"}}
+
+	table := []struct {
+		term    string
+		want    string
+		wantErr error
+	}{
+		{`lower(name) = "x"`, "LCASE(name) = x", nil},
+		{`testdriveronly(name) AND testDriverOnly(name, 1, 2)`, "testdriveronly(name) AND testdriveronly(name, 1, 2)", nil},
+		{`testDriverOnly()`, "", ErrSyntax},
+		{`testDriverOnly(name, "x", true)`, "", ErrMismatch},
+	}
+	for i, v := range table {
+		ast, haveErr := Parse(v.term)
+		have := ""
+		if haveErr == nil {
+			var sb strings.Builder
+			haveErr = ast.Format(FormatArgs{Writer: &sb, Format: f})
+			have = sb.String()
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestCall %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestCall %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestCall %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+
+	ast, _ := Parse(`testDriverOnly(name)`)
+	if _, err := Eval(ast, nil, Opt{}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("TestCall eval has error %v but wanted %v", err, ErrUnsupported)
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-VISIT
+func TestVisit(t *testing.T) {
+	table := []struct {
+		term    string
+		want    string
+		wantErr error
+	}{
+		{`a = 1 AND (b IN (2, "x") OR NOT (c.d LIKE "x%"))`, `a=$1 AND (b IN ($2,$3) OR NOT (c.d LIKE $4)) [1 2 x x%]`, nil},
+		{`-len(a) > ? AND e IS NULL AND f BETWEEN [1, 2)`, `-len(a)>? AND e IS NULL AND f BETWEEN($1,$2) [1 2]`, nil},
+	}
+	for i, v := range table {
+		ast, haveErr := Parse(v.term)
+		tv := &testVisitor{}
+		if haveErr == nil {
+			haveErr = Visit(ast, tv)
+		}
+		have := tv.sb.String() + " " + fmt.Sprintf("%v", tv.args)
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestVisit %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestVisit %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestVisit %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-WALK
+func TestWalk(t *testing.T) {
+	ast, err := Parse(`a = 1 AND (b = 2 OR c IN (3, 4))`)
+	if err != nil {
+		t.Fatalf("TestWalk parse error %v", err)
+	}
+	count := 0
+	err = Walk(ast, func(n AstNode) error {
+		count++
+		if _, ok := n.(*unaryNode); ok {
+			return SkipChildren
+		}
+		return nil
+	})
+	if err != nil || count != 5 {
+		t.Fatalf("TestWalk has count %v error %v but wanted 5", count, err)
+	}
+}
+
+// testVisitor renders a SQL-like string, collecting values as args.
+type testVisitor struct {
+	sb   strings.Builder
+	args []any
+}
+
+func (v *testVisitor) field(n AstNode) error {
+	path, ok := FieldOf(n)
+	if !ok {
+		return Visit(n, v)
+	}
+	v.sb.WriteString(path.String())
+	return nil
+}
+
+func (v *testVisitor) VisitBinary(n BinaryNode) error {
+	switch n.Keyword {
+	case AndKeyword, OrKeyword:
+		Visit(n.Lhs, v)
+		v.sb.WriteString(" " + n.Keyword + " ")
+		return Visit(n.Rhs, v)
+	case InKeyword, NotInKeyword:
+		v.field(n.Lhs)
+		v.sb.WriteString(" " + n.Keyword + " ")
+		return Visit(n.Rhs, v)
+	}
+	v.field(n.Lhs)
+	v.sb.WriteString(n.Keyword)
+	return Visit(n.Rhs, v)
+}
+
+func (v *testVisitor) VisitUnary(n UnaryNode) error {
+	v.sb.WriteString(strings.TrimSpace(n.Keyword + " "))
+	if n.Keyword == NotKeyword {
+		v.sb.WriteString(" ")
+	}
+	return Visit(n.Child, v)
+}
+
+func (v *testVisitor) VisitGroup(n GroupNode) error {
+	v.sb.WriteString("(")
+	err := Visit(n.Child, v)
+	v.sb.WriteString(")")
+	return err
+}
+
+func (v *testVisitor) VisitList(n ListNode) error {
+	v.sb.WriteString("(")
+	for i, item := range n.Items {
+		if i > 0 {
+			v.sb.WriteString(",")
+		}
+		Visit(item, v)
+	}
+	v.sb.WriteString(")")
+	return nil
+}
+
+func (v *testVisitor) VisitValue(n ValueNode) error {
+	v.args = append(v.args, n.Value)
+	v.sb.WriteString(fmt.Sprintf("$%v", len(v.args)))
+	return nil
+}
+
+func (v *testVisitor) VisitParam(p Param) error {
+	v.sb.WriteString(p.Text)
+	return nil
+}
+
+func (v *testVisitor) VisitField(n FieldNode) error {
+	v.sb.WriteString(n.Path.String())
+	return nil
+}
+
+func (v *testVisitor) VisitMatch(n MatchNode) error {
+	v.field(n.Lhs)
+	v.sb.WriteString(" " + n.Keyword + " ")
+	return Visit(n.Pattern, v)
+}
+
+func (v *testVisitor) VisitPredicate(n PredicateNode) error {
+	v.field(n.Field)
+	v.sb.WriteString(" " + n.Keyword)
+	return nil
+}
+
+func (v *testVisitor) VisitBetween(n BetweenNode) error {
+	v.field(n.Field)
+	v.sb.WriteString(" " + n.Keyword + "(")
+	Visit(n.Lower, v)
+	v.sb.WriteString(",")
+	Visit(n.Upper, v)
+	v.sb.WriteString(")")
+	return nil
+}
+
+func (v *testVisitor) VisitCall(n CallNode) error {
+	v.sb.WriteString(n.Name + "(")
+	for _, arg := range n.Args {
+		v.field(arg)
+	}
+	v.sb.WriteString(")")
+	return nil
+}
+
+// ---------------------------------------------------------
+// TEST-TRANSFORM
+func TestTransform(t *testing.T) {
+	table := []struct {
+		term    string
+		t       Transform
+		want    string
+		wantErr error
+	}{
+		{"(a = 1) AND ((b = 2))", StripGroups, "a = 1 AND b = 2", nil},
+		{"(a OR b) AND c", StripGroups, "(a OR b) AND c", nil},
+		{"a AND (b AND (c AND d))", Flatten, "a AND b AND c AND d", nil},
+		{"(a OR b) OR (c OR d)", Flatten, "a OR b OR c OR d", nil},
+		{"a AND (b OR c)", Flatten, "a AND (b OR c)", nil},
+		{"NOT (a = 1 AND b IN (1, 2))", PushNot, "(a != 1 OR b NOT IN (1, 2))", nil},
+		{"NOT (a OR NOT (b < 2))", PushNot, "(NOT a AND (b < 2))", nil},
+		{"NOT NOT NOT (a != 1)", PushNot, "(a == 1)", nil},
+		{"NOT (a < 1 OR true)", PushNot, "(NOT (a < 1) AND false)", nil},
+		{"a = -(1) AND 2 > 1", FoldConstants, "a = -1", nil},
+		{"a = 1 OR -(2) < 0", FoldConstants, "true", nil},
+		{"NOT (1 = 2) AND a = 1", FoldConstants, "a = 1", nil},
+		{"NOT (1 = 2) AND a", FoldConstants, "true AND a", nil},
+		{"a OR false OR (b AND false)", FoldConstants, "a OR false OR (b AND false)", nil},
+		{"a = 1 OR false OR (b = 1 AND false)", FoldConstants, "a = 1", nil},
+		{"(a = 1 OR true) AND (b LIKE c OR false)", FoldConstants, "(b LIKE c)", nil},
+		{"a = (5) AND b = (c)", FoldConstants, "a = 5 AND b = (c)", nil},
+		{"a = 1 AND b = 2 AND a = 1", Dedupe, "a = 1 AND b = 2", nil},
+		{`a = 1 OR a = "1" OR a = 1.0`, Dedupe, "a = 1 OR a = 1 OR a = 1", nil},
+		{"a = ? OR a = ?", Dedupe, "a = ? OR a = ?", nil},
+		{"a = ? OR (b AND a = ?)", Normalize, "a = ? OR b AND a = ?", nil},
+		{"a AND (b OR c)", ToDNF, "a AND b OR a AND c", nil},
+		{"(a OR b) AND (c OR d)", ToDNF, "a AND c OR a AND d OR b AND c OR b AND d", nil},
+		{"NOT (a AND b) AND c", ToDNF, "NOT a AND c OR NOT b AND c", nil},
+		{"a OR b AND c", ToCNF, "(a OR b) AND (a OR c)", nil},
+		{"a OR (b AND a)", ToCNF, "(a OR b) AND a", nil},
+		{"(a AND NOT (b OR b)) OR (c AND c)", Normalize, "a AND NOT b OR c", nil},
+		{"(a OR b)" + strings.Repeat(" AND (a OR b)", 10), ToDNF, "", ErrUnsupported},
+	}
+	for i, v := range table {
+		ast, haveErr := Parse(v.term)
+		have := ""
+		if haveErr == nil {
+			ast, haveErr = v.t(ast)
+		}
+		if haveErr == nil {
+			var sb strings.Builder
+			haveErr = ast.Format(FormatArgs{Writer: &sb, Format: _defaultFormat})
+			have = sb.String()
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestTransform %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestTransform %v has error %v but exptected %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestTransform %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+
+	// Folding keeps the evaluated result.
+	item := map[string]any{"a": 5, "b": "x", "c": true}
+	for i, term := range []string{"a AND true", "true AND a", "a OR false", "a AND false",
+		"c AND true", "NOT (a = 1) AND true", "a = 5 AND (b OR true)", "(c OR false) AND b = x"} {
+		ast, _ := Parse(term)
+		folded, err := Normalize(ast)
+		if err != nil {
+			t.Fatalf("TestTransform eval %v has error %v", i, err)
+		}
+		want, wantErr := Eval(ast, item, Opt{})
+		have, haveErr := Eval(folded, item, Opt{})
+		if have != want || (haveErr == nil) != (wantErr == nil) {
+			t.Fatalf("TestTransform eval %v has %v %v but wanted %v %v", i, have, haveErr, want, wantErr)
+		}
+	}
+
+	// Transforms don't modify their input.
+	term := "NOT (a = 1 AND (b OR c)) AND a = 1"
+	ast, _ := Parse(term)
+	Chain(Normalize, ToDNF, ToCNF)(ast)
+	var sb strings.Builder
+	ast.Format(FormatArgs{Writer: &sb, Format: _defaultFormat})
+	if have := sb.String(); have != term {
+		t.Fatalf("TestTransform modified input to \"%v\"", have)
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-FINGERPRINT
+func TestFingerprint(t *testing.T) {
+	table := []struct {
+		a, b     string
+		abstract bool
+		want     bool
+	}{
+		{"a = 1 AND b = 2", "a = 1 AND b = 2", false, true},
+		{"a=1 AND b=2", "(a = 1) and b = 2", false, true},
+		{"a = 1 AND (b = 2 AND c)", "a = 1 AND b = 2 AND c", false, true},
+		{"NOT (a = 1 OR b)", "a != 1 AND NOT b", false, true},
+		{"id = 5", "id = 6", false, false},
+		{"id = 5", "id = 6", true, true},
+		{"id = 5", "id = 5.0", false, false},
+		{"id = 5", "id = ?", true, true},
+		{"name = bob", "name = sue", true, true},
+		{"name = bob", "other = bob", true, false},
+		{"id IN (1, 2)", "id IN (3, 4, 5)", true, true},
+		{"id IN (1, 2)", "id IN (3, 4, 5)", false, false},
+		{`name LIKE "a%" AND at BETWEEN [t"2026-01-01T00:00:00Z", t"2026-02-01T00:00:00Z")`, `name LIKE "b%" AND at BETWEEN [1, 2)`, true, true},
+		{"at BETWEEN [1, 2)", "at BETWEEN [1, 2]", true, false},
+		{"lower(a) = b", "lower(c) = b", true, false},
+		{"a = 1 AND b = 2", "b = 2 AND a = 1", false, false},
+		{`a IN ("p", "q")`, `a IN ("p string:q")`, false, false},
+		{`a = "x) (y"`, `a = "x" AND y`, false, false},
+	}
+	for i, v := range table {
+		a, errA := Parse(v.a)
+		b, errB := Parse(v.b)
+		if errA != nil || errB != nil {
+			t.Fatalf("TestFingerprint %v has parse errors %v %v", i, errA, errB)
+		}
+		a, _ = Normalize(a)
+		b, _ = Normalize(b)
+		opts := FingerprintOptions{AbstractValues: v.abstract}
+		have := Fingerprint(a, opts) == Fingerprint(b, opts)
+		if have != v.want {
+			t.Fatalf("TestFingerprint %v has %v but wanted %v", i, have, v.want)
+		} else if !v.abstract && Equal(a, b) != v.want {
+			t.Fatalf("TestFingerprint %v has Equal %v but wanted %v", i, !v.want, v.want)
+		}
+	}
+
+	// Fingerprints are used as persistent keys, so they must not change
+	// without a new fingerprintVersion.
+	ast, _ := Parse("id = 5 AND name IN (a, b)")
+	if have := Fingerprint(ast, FingerprintOptions{AbstractValues: true}); have != "32fdd3f99f0e1d1e365f3754935d0590" {
+		t.Fatalf("TestFingerprint has stable fingerprint %v", have)
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-JSON
+func TestJSON(t *testing.T) {
+	table := []struct {
+		term string
+		args []any
+	}{
+		{"a = 1 AND (b = 2.5 OR c)", nil},
+		{"name = bob AND title != \"bob\"", nil},
+		{"a.b[0] = -1", nil},
+		{`"a.b" = 1 AND "c[0]" IN (d.e)`, nil},
+		{"id IN (1, 2, 3) AND name NOT IN (a, b)", nil},
+		{"NOT (active = true) AND deleted = null", nil},
+		{`name LIKE "a%" OR name STARTSWITH b OR name CONTAINS c OR name MATCHES "^d"`, nil},
+		{"a IS NULL AND b IS NOT NULL AND EXISTS(c.d)", nil},
+		{`at BETWEEN [t"2026-01-01T00:00:00Z", t"2026-02-01T00:00:00.5Z") AND ttl > d"1h30m"`, nil},
+		{"lower(name) = bob AND len(tags) > 2", nil},
+		{"id = ? AND name = :name", nil},
+		{"id = ? AND name = :name", []any{int64(10), NamedArg{Name: "name", Value: "bob"}}},
+	}
+	for i, v := range table {
+		want, err := Parse(v.term)
+		if err == nil {
+			err = Bind(want, v.args...)
+		}
+		if err != nil {
+			t.Fatalf("TestJSON %v has parse error %v", i, err)
+		}
+		data, err := MarshalAst(want)
+		if err != nil {
+			t.Fatalf("TestJSON %v has marshal error %v", i, err)
+		}
+		have, err := UnmarshalAst(data)
+		if err != nil {
+			t.Fatalf("TestJSON %v has unmarshal error %v for %s", i, err, data)
+		} else if !Equal(have, want) {
+			t.Fatalf("TestJSON %v has %v but wanted %v", i, nodeKey(have), nodeKey(want))
+		}
+	}
+
+	// The document format is persistent, so it must not change.
+	ast, _ := Parse("id = 5 AND a.b IN (x, 1.5)")
+	data, _ := MarshalAst(ast)
+	golden := `{"version":1,"ast":{"op":"AND","args":[{"op":"=","args":[{"op":"FIELD","field":"id"},{"op":"VALUE","type":"int","value":5}]},{"op":"IN","args":[{"op":"FIELD","field":"a.b"},{"op":"LIST","args":[{"op":"VALUE","type":"string","value":"x"},{"op":"VALUE","type":"float","value":1.5}]}]}]}}`
+	if string(data) != golden {
+		t.Fatalf("TestJSON has document %s", data)
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-JSON-ERRORS
+func TestJSONErrors(t *testing.T) {
+	table := []struct {
+		doc     string
+		wantErr error
+	}{
+		{`{"version":2,"ast":{"op":"FIELD","field":"a"}}`, ErrUnsupported},
+		{`{"version":1}`, ErrBadRequest},
+		{`{"version":1,"ast":`, ErrBadRequest},
+		{`{"version":1,"ast":{"op":"XOR","args":[{"op":"FIELD","field":"a"},{"op":"FIELD","field":"b"}]}}`, ErrBadRequest},
+		{`{"version":1,"ast":{"op":"=","args":[{"op":"FIELD","field":"a"}]}}`, ErrBadRequest},
+		{`{"version":1,"ast":{"op":"IN","args":[{"op":"FIELD","field":"a"},{"op":"VALUE","type":"int","value":1}]}}`, ErrBadRequest},
+		{`{"version":1,"ast":{"op":"=","args":[{"op":"FIELD","field":"a"},{"op":"VALUE","type":"int","value":"x"}]}}`, ErrBadRequest},
+		{`{"version":1,"ast":{"op":"=","args":[{"op":"FIELD","field":"a"},{"op":"VALUE","type":"blob","value":1}]}}`, ErrBadRequest},
+		{`{"version":1,"ast":{"op":"=","args":[{"op":"CALL","name":"nope"},{"op":"VALUE","type":"int","value":1}]}}`, ErrSyntax},
+		{`{"version":1,"ast":{"op":"IS NULL","args":[{"op":"VALUE","type":"int","value":1}]}}`, ErrSyntax},
+		{`{"version":1,"ast":{"op":"FIELD","field":"a","name":"a.b"}}`, ErrBadRequest},
+	}
+	for i, v := range table {
+		_, haveErr := UnmarshalAst([]byte(v.doc))
+		if !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestJSONErrors %v has error %v but wanted %v", i, haveErr, v.wantErr)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-BUILD
+func TestBuild(t *testing.T) {
+	field := func(s string) AstNode {
+		n, _ := NewField(s)
+		return n
+	}
+	value := func(v any) AstNode {
+		n, _ := NewValue(v)
+		return n
+	}
+	binary := func(keyword string, lhs, rhs AstNode) AstNode {
+		n, err := NewBinary(keyword, lhs, rhs)
+		if err != nil {
+			t.Fatalf("TestBuild has error %v", err)
+		}
+		return n
+	}
+	table := []struct {
+		build   func() (AstNode, error)
+		term    string
+		wantErr error
+	}{
+		{func() (AstNode, error) {
+			return NewBinary(AndKeyword, binary(GreaterEqualKeyword, field("age"), value(21)), binary(InKeyword, field("name"), NewList(value("a"), value("b"))))
+		}, `age >= 21 AND name IN ("a", "b")`, nil},
+		{func() (AstNode, error) {
+			return NewBinary(AndKeyword, field("a"), binary(OrKeyword, field("b"), field("c")))
+		}, "a AND (b OR c)", nil},
+		{func() (AstNode, error) {
+			return NewBinary(OrKeyword, binary(OrKeyword, field("a"), field("b")), field("c"))
+		}, "a OR b OR c", nil},
+		{func() (AstNode, error) {
+			return NewBinary(OrKeyword, field("a"), binary(OrKeyword, field("b"), field("c")))
+		}, "a OR (b OR c)", nil},
+		{func() (AstNode, error) {
+			return NewNot(binary(AndKeyword, field("a"), field("b"))), nil
+		}, "NOT (a AND b)", nil},
+		{func() (AstNode, error) {
+			return NewBinary(EqualKeyword, field("a.b[0]"), NewNeg(value(1.5)))
+		}, "a.b[0] == -1.5", nil},
+		{func() (AstNode, error) {
+			return NewBinary(EqualKeyword, field("a"), NewNeg(field("b")))
+		}, "a == -b", nil},
+		{func() (AstNode, error) {
+			return NewBinary(AssignKeyword, field("a"), value(nil))
+		}, "a = null", nil},
+		{func() (AstNode, error) {
+			return NewMatch(LikeKeyword, field("name"), value("a%"))
+		}, `name LIKE "a%"`, nil},
+		{func() (AstNode, error) {
+			return NewPredicate(IsNotNullKeyword, field("a"))
+		}, "a IS NOT NULL", nil},
+		{func() (AstNode, error) {
+			return NewPredicate(ExistsKeyword, field("a.b"))
+		}, "EXISTS(a.b)", nil},
+		{func() (AstNode, error) {
+			return NewBetween(field("at"), value(1), value(2), false, true)
+		}, "at BETWEEN [1, 2)", nil},
+		{func() (AstNode, error) {
+			lower, _ := NewCall("lower", field("name"))
+			return NewBinary(EqualKeyword, lower, value("bob"))
+		}, "lower(name) == bob", nil},
+		// Errors
+		{func() (AstNode, error) { return NewBinary(InKeyword, field("a"), value(1)) }, "", ErrSyntax},
+		{func() (AstNode, error) { return NewBinary(InKeyword, field("a"), NewList()) }, "", ErrSyntax},
+		{func() (AstNode, error) { return NewBinary(LikeKeyword, field("a"), value("b")) }, "", ErrSyntax},
+		{func() (AstNode, error) { return NewMatch(EqualKeyword, field("a"), value("b")) }, "", ErrSyntax},
+		{func() (AstNode, error) { return NewPredicate(IsNullKeyword, value(1)) }, "", ErrSyntax},
+		{func() (AstNode, error) { return NewCall("nope") }, "", ErrSyntax},
+		{func() (AstNode, error) { return NewValue(struct{}{}) }, "", ErrBadRequest},
+		{func() (AstNode, error) { return NewField("a..b") }, "", ErrSyntax},
+	}
+	for i, v := range table {
+		have, haveErr := v.build()
+		if v.wantErr != nil {
+			if !errors.Is(haveErr, v.wantErr) {
+				t.Fatalf("TestBuild %v has error %v but wanted %v", i, haveErr, v.wantErr)
+			}
+			continue
+		}
+		want, err := Parse(v.term)
+		if haveErr != nil || err != nil {
+			t.Fatalf("TestBuild %v has errors %v %v", i, haveErr, err)
+		} else if !Equal(have, want) {
+			t.Fatalf("TestBuild %v has %v but wanted %v", i, nodeKey(have), nodeKey(want))
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-DIALECT
+func TestDialect(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 500000000, time.UTC)
+	table := []struct {
+		dialect string
+		term    string
+		args    []any
+		want    string
+		wantErr error
+	}{
+		{PostgresDialect, `name = "it's" AND active = true`, nil, `"name" = 'it''s' AND "active" = TRUE`, nil},
+		{MySQLDialect, `name = "it's" AND active = true`, nil, "`name` = 'it''s' AND `active` = TRUE", nil},
+		{SQLiteDialect, `name = "it's" AND active = true`, nil, `"name" = 'it''s' AND "active" = 1`, nil},
+		{PostgresDialect, `path = ?`, []any{`a\b`}, `"path" = 'a\b'`, nil},
+		{MySQLDialect, `path = ?`, []any{`a\b`}, "`path` = 'a\\\\b'", nil},
+		{PostgresDialect, "a.b != 1.5 OR c[0] IS NULL", nil, `"a"->>'b' <> 1.5 OR "c"->>0 IS NULL`, nil},
+		{PostgresDialect, "id = ? AND name IN (?, $3)", nil, `"id" = $1 AND "name" IN ($2, $3)`, nil},
+		{MySQLDialect, "id = ? AND name IN (?, ?)", nil, "`id` = ? AND `name` IN (?, ?)", nil},
+		{SQLiteDialect, "id = ? AND name = :name", nil, `"id" = ?1 AND "name" = :name`, nil},
+		{PostgresDialect, "at > ? AND ttl < ?", []any{at, 90 * time.Minute}, `"at" > TIMESTAMPTZ '2026-01-02 03:04:05.5Z' AND "ttl" < INTERVAL '5400 seconds'`, nil},
+		{MySQLDialect, "at > ?", []any{at}, "`at` > '2026-01-02 03:04:05.5'", nil},
+		{SQLiteDialect, "at BETWEEN 1 AND 2 AND b IS NOT NULL", nil, `"at" BETWEEN 1 AND 2 AND "b" IS NOT NULL`, nil},
+		{PostgresDialect, `lower(name) MATCHES "^a" AND len(tags) > 2`, nil, `LOWER("name") ~ '^a' AND CHAR_LENGTH("tags") > 2`, nil},
+		{MySQLDialect, `NOT (name MATCHES "^a")`, nil, "NOT (`name` REGEXP '^a')", nil},
+		{PostgresDialect, `name LIKE ?`, []any{`50\%%`}, `"name" LIKE '50\%%' ESCAPE '\'`, nil},
+		{MySQLDialect, `name LIKE ?`, []any{`50\%%`}, "`name` LIKE '50\\\\%%' ESCAPE '\\\\'", nil},
+		{SQLiteDialect, `name LIKE ? OR name = "a"`, []any{"a\\_%"}, `"name" LIKE 'a\_%' ESCAPE '\' OR "name" = 'a'`, nil},
+		// Errors
+		{PostgresDialect, "name STARTSWITH a", nil, "", ErrUnsupported},
+		{PostgresDialect, "EXISTS(a)", nil, "", ErrUnsupported},
+		{PostgresDialect, "id = :id", nil, "", ErrUnsupported},
+		{MySQLDialect, "id = $1", nil, "", ErrUnsupported},
+		{MySQLDialect, "a[0] = 1", nil, "", ErrUnsupported},
+		{SQLiteDialect, "ttl < ?", []any{time.Hour}, "", ErrUnsupported},
+		{PostgresDialect, "a = null", nil, "", ErrUnsupported},
+		{MySQLDialect, "a != null", nil, "", ErrUnsupported},
+		{SQLiteDialect, "a = ?", []any{nil}, "", ErrUnsupported},
+	}
+	for i, v := range table {
+		f, ok := LookupDialect(v.dialect)
+		if !ok {
+			t.Fatalf("TestDialect %v has no dialect %v", i, v.dialect)
+		}
+		ast, haveErr := Parse(v.term)
+		have := ""
+		if haveErr == nil {
+			haveErr = Bind(ast, v.args...)
+		}
+		if haveErr == nil {
+			var sb strings.Builder
+			haveErr = ast.Format(FormatArgs{Writer: &sb, Format: f})
+			have = sb.String()
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestDialect %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestDialect %v has error %v but wanted %v", i, haveErr, v.wantErr)
+		} else if v.wantErr == nil && have != v.want {
+			t.Fatalf("TestDialect %v has \"%v\" but wanted \"%v\"", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-MONGO
+func TestMongo(t *testing.T) {
+	table := []struct {
+		term    string
+		args    []any
+		want    string
+		wantErr error
+	}{
+		{"age >= 21", nil, `{"age":{"$gte":21}}`, nil},
+		{"age >= 21 AND name IN (a, b) AND x != 1.5", nil, `{"$and":[{"age":{"$gte":21}},{"name":{"$in":["a","b"]}},{"x":{"$ne":1.5}}]}`, nil},
+		{"a = 1 OR (b = 2 OR c NOT IN (3))", nil, `{"$or":[{"a":{"$eq":1}},{"b":{"$eq":2}},{"c":{"$nin":[3]}}]}`, nil},
+		{"NOT (a = 1 AND b)", nil, `{"$nor":[{"$and":[{"a":{"$eq":1}},{"b":{"$eq":true}}]}]}`, nil},
+		{"a.b[2] < ? AND c == :c", []any{-1, NamedArg{Name: "c", Value: "x"}}, `{"$and":[{"a.b.2":{"$lt":-1}},{"c":{"$eq":"x"}}]}`, nil},
+		{`name LIKE "a_c%" AND name STARTSWITH "a.b" AND name CONTAINS x AND name MATCHES "^a+$"`, nil,
+			`{"$and":[{"name":{"$regex":"(?s)^a.c.*$"}},{"name":{"$regex":"^a\\.b"}},{"name":{"$regex":"x"}},{"name":{"$regex":"^a+$"}}]}`, nil},
+		{"a IS NULL OR b IS NOT NULL OR EXISTS(c)", nil, `{"$or":[{"a":{"$type":"null"}},{"b":{"$exists":true,"$ne":null}},{"c":{"$exists":true}}]}`, nil},
+		{"a BETWEEN 1 AND 2 AND b BETWEEN (1, 2]", nil, `{"$and":[{"a":{"$gte":1,"$lte":2}},{"b":{"$gt":1,"$lte":2}}]}`, nil},
+		{`at > t"2026-01-02T00:00:00Z"`, nil, `{"at":{"$gt":"2026-01-02T00:00:00Z"}}`, nil},
+		{"true", nil, `{}`, nil},
+		// Errors
+		{"lower(name) = a", nil, "", ErrUnsupported},
+		{"a = ?", nil, "", ErrUnsupported},
+		{"a = -b", nil, "", ErrUnsupported},
+		{"1 = a", nil, "", ErrUnsupported},
+	}
+	for i, v := range table {
+		ast, haveErr := Parse(v.term)
+		have := ""
+		if haveErr == nil {
+			haveErr = Bind(ast, v.args...)
+		}
+		if haveErr == nil {
+			var filter map[string]any
+			if filter, haveErr = MongoFilter(ast); haveErr == nil {
+				data, _ := json.Marshal(filter)
+				have = string(data)
+			}
+		}
+
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestMongo %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestMongo %v has error %v but wanted %v", i, haveErr, v.wantErr)
+		} else if have != v.want {
+			t.Fatalf("TestMongo %v has %v but wanted %v", i, have, v.want)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-IDENTIFIER
+func TestIdentifier(t *testing.T) {
+	table := []struct {
+		f     Format
+		field string
+		want  string
+	}{
+		{_defaultFormat, "order", "order == 1"},
+		{_postgresFormat, "order", `"order" = 1`},
+		{_postgresFormat, "user-name", `"user-name" = 1`},
+		{_postgresFormat, `a" = 1 OR "b`, `"a"" = 1 OR ""b" = 1`},
+		{_mysqlFormat, "a`b", "`a``b` = 1"},
+		{_postgresFormat, "doc.tags[0]", `"doc"->'tags'->>0 = 1`},
+		{_postgresFormat, "doc.it's", `"doc"->>'it''s' = 1`},
+		{_sqliteFormat, "t.order", `"t"."order" = 1`},
+		{plainFormat{}, "doc.tags[0]", "doc.tags[0] == 1"},
+		{bracketFormat{}, "order", "[order] == 1"},
+		{bracketFormat{}, "doc.tags[0]", "[doc].[tags][0] == 1"},
+		{bracketFormat{}, "a]b", "[a]]b] == 1"},
+	}
+	for i, v := range table {
+		field, err := NewField(v.field)
+		if err != nil {
+			t.Fatalf("TestIdentifier %v has error %v", i, err)
+		}
+		one, _ := NewValue(1)
+		ast, _ := NewBinary(EqualKeyword, field, one)
+		var sb strings.Builder
+		err = ast.Format(FormatArgs{Writer: &sb, Format: v.f})
+		if err != nil {
+			t.Fatalf("TestIdentifier %v has error %v", i, err)
+		} else if have := sb.String(); have != v.want {
+			t.Fatalf("TestIdentifier %v has %v but wanted %v", i, have, v.want)
+		}
+	}
+}
+
+// plainFormat implements only Format, as drivers written
+// before the optional extensions do.
+type plainFormat struct{}
+
+func (plainFormat) Keyword(s string) string {
+	return _defaultFormat.Keyword(s)
+}
+
+func (plainFormat) Value(v interface{}) (string, error) {
+	return fmt.Sprintf("%v", v), nil
+}
+
+// bracketFormat quotes identifiers but leaves paths to the package.
+type bracketFormat struct {
+	plainFormat
+}
+
+func (bracketFormat) Identifier(name string) (string, error) {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]", nil
+}
+
+// ---------------------------------------------------------
+// TEST-LIMITS
+func TestLimits(t *testing.T) {
+	nested := func(n int) string {
+		return strings.Repeat("(", n) + "a = 1" + strings.Repeat(")", n)
+	}
+	list := func(n int) string {
+		items := make([]string, n)
+		for i := range items {
+			items[i] = fmt.Sprint(i)
+		}
+		return "a IN (" + strings.Join(items, ", ") + ")"
+	}
+	fields := func(n int) string {
+		terms := make([]string, n)
+		for i := range terms {
+			terms[i] = fmt.Sprintf("f%v = 1", i)
+		}
+		return strings.Join(terms, " AND ")
+	}
+	table := []struct {
+		term    string
+		opts    ParseOptions
+		wantErr error
+	}{
+		{"a = 1", ParseOptions{MaxLength: 5}, nil},
+		{"a = 10", ParseOptions{MaxLength: 5}, ErrTooLong},
+		{strings.Repeat(" ", DefaultMaxLength+1), ParseOptions{}, ErrTooLong},
+		{"a = 1 AND b = 2", ParseOptions{MaxTokens: 7}, nil},
+		{"a = 1 AND b = 2", ParseOptions{MaxTokens: 6}, ErrTooManyTokens},
+		{nested(10), ParseOptions{MaxDepth: 12}, nil},
+		{nested(10), ParseOptions{MaxDepth: 11}, ErrTooDeep},
+		{strings.Repeat("NOT ", 20) + "a", ParseOptions{MaxDepth: 10}, ErrTooDeep},
+		{nested(DefaultMaxDepth), ParseOptions{}, ErrTooDeep},
+		{nested(DefaultMaxDepth), ParseOptions{MaxDepth: -1}, nil},
+		{fields(1000), ParseOptions{MaxFields: -1}, nil},
+		{list(3), ParseOptions{MaxListItems: 3}, nil},
+		{list(4), ParseOptions{MaxListItems: 3}, ErrTooManyItems},
+		{list(DefaultMaxListItems + 1), ParseOptions{}, ErrTooManyItems},
+		{"abs(1) = a", ParseOptions{MaxListItems: 1}, nil},
+		{fields(3) + " AND f0 = 2", ParseOptions{MaxFields: 3}, nil},
+		{fields(4), ParseOptions{MaxFields: 3}, ErrTooManyFields},
+		{fields(DefaultMaxFields + 1), ParseOptions{}, ErrTooManyFields},
+	}
+	for i, v := range table {
+		_, haveErr := ParseWith(v.term, v.opts)
+		if v.wantErr == nil && haveErr != nil {
+			t.Fatalf("TestLimits %v expected no error but has %v", i, haveErr)
+		} else if v.wantErr != nil && !errors.Is(haveErr, v.wantErr) {
+			t.Fatalf("TestLimits %v has error %v but wanted %v", i, haveErr, v.wantErr)
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// TEST-ROUND-TRIP
+func TestRoundTrip(t *testing.T) {
+	table := []string{
+		`a`,
+		`NOT a`,
+		`a = 1 AND (b = 2 OR c = 3)`,
+		`a = "hello world"`,
+		`a = "AND"`,
+		`a = "10"`,
+		`a = "b.c"`,
+		`"a b" = 1`,
+		`a.b[0] = 1`,
+		`a = 1.0`,
+		`a = -1 AND b = -2.5`,
+		`-a > 1`,
+		`NOT -a`,
+		`a = true AND b != null`,
+		`a IN (1, "two", 3.0) AND b NOT IN (x)`,
+		`a LIKE "%x%" OR b STARTSWITH "y" OR c CONTAINS "z" OR d MATCHES "^a+$"`,
+		`a IS NULL AND b IS NOT NULL AND EXISTS c`,
+		`a BETWEEN 1 AND 5`,
+		`a BETWEEN [1, 5]`,
+		`abs(a) > 1 AND lower(b) = "x"`,
+		`a = $1 OR b = :name OR c = ?`,
+		`a = t"2026-01-02T03:04:05.5+02:00" AND b < d"1h30m"`,
+		`t"2020-01-01T00:00:00Z" = a AND d"5m" > b`,
+		`null = a OR 1.5 < b`,
+	}
+	for i, term := range table {
+		if err := checkRoundTrip(term); err != nil {
+			t.Fatalf("TestRoundTrip %v %v", i, err)
+		}
+	}
+}
+
+// checkRoundTrip answers an error if term parses but its
+// formatted text doesn't parse to the same tree. Parentheses
+// the formatter adds are ignored, as are trees with a
+// construct the default format can't write back.
+func checkRoundTrip(term string) error {
+	ast, err := Parse(term)
+	if err != nil || hasFormatGap(ast, NoFormatContext) {
+		return nil
+	}
+	var sb strings.Builder
+	err = ast.Format(FormatArgs{Writer: &sb, Format: roundTripFormat{_defaultFormat}})
+	if errors.Is(err, ErrUnsupported) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%q format error %w", term, err)
+	}
+	formatted := sb.String()
+	ast2, err := Parse(formatted)
+	if err != nil {
+		return fmt.Errorf("%q formatted as %q, which has parse error %w", term, formatted, err)
+	}
+	want, _ := StripGroups(ast)
+	have, _ := StripGroups(ast2)
+	if !Equal(want, have) {
+		return fmt.Errorf("%q formatted as %q, which has %v but wanted %v", term, formatted, nodeKey(have), nodeKey(want))
+	}
+	return nil
+}
+
+// hasFormatGap answers true if n has a construct the default
+// format writes as something else: an exclusive BETWEEN, which
+// is expanded, or a path on the RHS, which is a string.
+func hasFormatGap(n AstNode, ctx FormatContext) bool {
+	switch t := n.(type) {
+	case *betweenNode:
+		if t.LowerExclusive || t.UpperExclusive {
+			return true
+		}
+	case *fieldNode:
+		return ctx == ValueContext
+	}
+	ctxs := childContexts(n, ctx)
+	for i, kid := range children(n) {
+		if hasFormatGap(kid, ctxs[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// roundTripFormat is the default format with values written
+// to parse back as the same type: strings are quoted and
+// floats keep their decimal point.
+type roundTripFormat struct {
+	Format
+}
+
+func (f roundTripFormat) Value(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return quoteForRoundTrip(t)
+	case float64:
+		s := strconv.FormatFloat(t, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, nil
+	}
+	return f.Format.Value(v)
+}
+
+func (f roundTripFormat) Path(path FieldPath) (string, error) {
+	if len(path) == 1 && !path[0].IsIndex {
+		return quoteForRoundTrip(path[0].Name)
+	}
+	return path.String(), nil
+}
+
+// quoteForRoundTrip answers s in quotes, or ErrUnsupported
+// if the quoted text doesn't scan back as s.
+func quoteForRoundTrip(s string) (string, error) {
+	quoted := `"` + s + `"`
+	tokens, err := scan(quoted, ParseOptions{})
+	if err != nil || len(tokens) != 1 || tokens[0].Token.Symbol != stringToken || strings.Trim(tokens[0].Text, `"`) != s {
+		return "", newUnsupportedError("string " + quoted)
+	}
+	return quoted, nil
+}
+
+// ---------------------------------------------------------
+// TEST-FUZZ
+func FuzzParse(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, term string) {
+		if err := checkRoundTrip(term); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzScan(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, term string) {
+		for _, lenient := range []bool{false, true} {
+			tokens, err := scan(term, ParseOptions{Lenient: lenient})
+			if err != nil {
+				continue
+			}
+			for _, tok := range tokens {
+				if tok == nil || tok.Token == nil {
+					t.Fatalf("%q has a nil token", term)
+				}
+			}
+		}
+	})
+}
+
+func FuzzNewAst(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, term string) {
+		fields := strings.Fields(term)
+		tokens := make([]any, len(fields))
+		for i, s := range fields {
+			tokens[i] = s
+		}
+		ast, err := NewAst(tokens...)
+		if err != nil {
+			return
+		}
+		var sb strings.Builder
+		ast.Format(FormatArgs{Writer: &sb, Format: _defaultFormat})
+	})
+}
+
+// fuzzSeeds adds a corpus of valid and broken expressions.
+func fuzzSeeds(f *testing.F) {
+	for _, term := range []string{
+		`a = 1 AND (b = "two" OR c.d[0] != 3.5)`,
+		`NOT a IN (1, 2, 3) OR b NOT IN ("x")`,
+		`a LIKE "%x" AND b MATCHES "^y" AND c STARTSWITH "z" AND d CONTAINS "w"`,
+		`a IS NULL OR b IS NOT NULL OR EXISTS c`,
+		`a BETWEEN [1, 5) AND b BETWEEN 1 AND 2`,
+		`abs(-a) >= 1 AND len(b) < 2`,
+		`a = $1 AND b = :name AND c = ?`,
+		`a = t"2026-01-02T03:04:05Z" AND b = d"1h"`,
+		`a = true AND b = null // comment`,
+		`a == -(1)`,
+		`a = `,
+		`((a)`,
+		`a =! b`,
+		`a<=>b!==c`,
+		`a = "unterminated`,
+		`$`,
+	} {
+		f.Add(term)
+	}
+}
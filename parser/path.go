@@ -0,0 +1,239 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ------------------------------------------------------------
+// FIELD-PATH
+
+// PathSegment is a single step in a field path: either
+// a field name or an array index.
+type PathSegment struct {
+	Name    string
+	Index   int
+	IsIndex bool
+}
+
+// FieldPath is a reference into a nested document,
+// i.e. "address.city" or "tags[0]".
+type FieldPath []PathSegment
+
+// ParseFieldPath converts a string in dot notation, with
+// optional array indexes, into a FieldPath.
+func ParseFieldPath(s string) (FieldPath, error) {
+	var path FieldPath
+	rest := s
+	for rest != "" {
+		if rest[0] == '[' {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, newSyntaxError("invalid path " + s)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return nil, newSyntaxError("invalid index in path " + s)
+			}
+			path = append(path, PathSegment{Index: i, IsIndex: true})
+			rest = rest[end+1:]
+			continue
+		}
+		if len(path) > 0 {
+			if rest[0] != '.' {
+				return nil, newSyntaxError("invalid path " + s)
+			}
+			rest = rest[1:]
+		}
+		end := strings.IndexAny(rest, ".[]")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 || (end < len(rest) && rest[end] == ']') {
+			return nil, newSyntaxError("invalid path " + s)
+		}
+		path = append(path, PathSegment{Name: rest[:end]})
+		rest = rest[end:]
+	}
+	if len(path) < 1 || path[0].IsIndex {
+		return nil, newSyntaxError("invalid path " + s)
+	}
+	return path, nil
+}
+
+// String answers the path in dot notation, i.e. "tags[0].name".
+func (p FieldPath) String() string {
+	var sb strings.Builder
+	for i, seg := range p {
+		if seg.IsIndex {
+			sb.WriteString("[" + strconv.Itoa(seg.Index) + "]")
+			continue
+		}
+		if i > 0 {
+			sb.WriteString(".")
+		}
+		sb.WriteString(seg.Name)
+	}
+	return sb.String()
+}
+
+// DotString answers the path in pure dot notation, where
+// indexes are also separated by dots, i.e. "tags.0.name".
+// This is the style used by Mongo.
+func (p FieldPath) DotString() string {
+	parts := make([]string, 0, len(p))
+	for _, seg := range p {
+		parts = append(parts, seg.String())
+	}
+	return strings.Join(parts, ".")
+}
+
+// Pointer answers the path as a JSON pointer (RFC 6901),
+// i.e. "/tags/0/name".
+func (p FieldPath) Pointer() string {
+	var sb strings.Builder
+	r := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, seg := range p {
+		sb.WriteString("/")
+		sb.WriteString(r.Replace(seg.String()))
+	}
+	return sb.String()
+}
+
+// JSONB answers the path in Postgres JSONB notation, where
+// the first segment is the column, i.e. "doc->'tags'->>0".
+// The final step uses ->> so the result is text.
+func (p FieldPath) JSONB() string {
+	if len(p) == 0 {
+		return ""
+	}
+	return p[0].String() + p[1:].jsonbSteps()
+}
+
+// jsonbSteps answers the -> and ->> operators that
+// follow the column in JSONB notation.
+func (p FieldPath) jsonbSteps() string {
+	var sb strings.Builder
+	for i, seg := range p {
+		if i == len(p)-1 {
+			sb.WriteString("->>")
+		} else {
+			sb.WriteString("->")
+		}
+		if seg.IsIndex {
+			sb.WriteString(strconv.Itoa(seg.Index))
+		} else {
+			sb.WriteString("'" + strings.ReplaceAll(seg.Name, "'", "''") + "'")
+		}
+	}
+	return sb.String()
+}
+
+// String answers the segment name, or the index as a string.
+func (s PathSegment) String() string {
+	if s.IsIndex {
+		return strconv.Itoa(s.Index)
+	}
+	return s.Name
+}
+
+// PathFormat is an optional extension to Format for rendering
+// field references, quoting and escaping them as the destination
+// requires. Plain field names arrive as single segment paths.
+// When the Format doesn't implement it, fields are rendered
+// with FieldPath.String().
+type PathFormat interface {
+	Path(p FieldPath) (string, error)
+}
+
+// IdentifierFormat is an optional extension to Format for quoting
+// and escaping identifiers, so a field named "order" or "user-name"
+// is safe in the output. Every named segment of a field reference
+// is passed through it, unless the Format renders whole paths with
+// PathFormat.
+type IdentifierFormat interface {
+	Identifier(name string) (string, error)
+}
+
+// formatPath answers p rendered by f, as a whole if f is a
+// PathFormat, or name by name if f is an IdentifierFormat.
+func formatPath(p FieldPath, f Format) (string, error) {
+	if pf, ok := f.(PathFormat); ok {
+		return pf.Path(p)
+	}
+	if idf, ok := f.(IdentifierFormat); ok {
+		return identifierPath(p, idf)
+	}
+	return p.String(), nil
+}
+
+// identifierPath answers p in dot notation, with each
+// name rendered by f.
+func identifierPath(p FieldPath, f IdentifierFormat) (string, error) {
+	var sb strings.Builder
+	for i, seg := range p {
+		if seg.IsIndex {
+			sb.WriteString("[" + strconv.Itoa(seg.Index) + "]")
+			continue
+		}
+		if i > 0 {
+			sb.WriteString(".")
+		}
+		s, err := f.Identifier(seg.Name)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(s)
+	}
+	return sb.String(), nil
+}
+
+// ------------------------------------------------------------
+// FIELD-NODE
+
+// fieldNode is a reference to a nested field, i.e. "address.city".
+type fieldNode struct {
+	Path FieldPath
+	Pos  Position
+}
+
+func (n *fieldNode) Format(args FormatArgs) error {
+	var s string
+	var err error
+	switch {
+	case args.Ctx == ValueContext:
+		s, err = args.Format.Value(n.Path.String())
+	default:
+		s, err = formatPath(n.Path, args.Format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = args.Writer.WriteString(s)
+	return err
+}
+
+func (n *fieldNode) Fields(args *FieldArgs) error {
+	if args.Ctx == ValueContext {
+		return nil
+	}
+	name := n.Path.String()
+	args.Fields = append(args.Fields, name)
+	args.Refs = append(args.Refs, FieldRef{Name: name, Path: n.Path, Pos: n.Pos})
+	return nil
+}
+
+func (n *fieldNode) Extract(any) error {
+	return nil
+}
+
+func (n *fieldNode) Eval(args EvalArgs) (any, error) {
+	if args.Ctx == ValueContext {
+		return n.Path.String(), nil
+	}
+	v, found := evalPath(args.Item, n.Path)
+	if !found && args.Opt.Strict {
+		return nil, newEvalError("no field " + n.Path.String())
+	}
+	return v, nil
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"strconv"
+	"text/scanner"
+	"unicode/utf8"
+)
+
+// Position describes a location in an expression string.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number, starting at 1 (character count per line)
+}
+
+// IsValid answers true if the position has been set.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+// advance answers the position after the text s, which
+// must not contain newlines.
+func (p Position) advance(s string) Position {
+	p.Offset += len(s)
+	p.Column += utf8.RuneCountInString(s)
+	return p
+}
+
+func newPosition(p scanner.Position) Position {
+	return Position{Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"reflect"
+)
+
+// ------------------------------------------------------------
+// PREDICATE-NODE
+
+// predicateNode tests a single field for presence or null
+// (IS NULL, IS NOT NULL, EXISTS). The tests are distinct:
+// IS NULL is true when the field is present with a null value,
+// IS NOT NULL when it is present with a non-null value, and
+// EXISTS when it is present at all. NOT EXISTS tests absence.
+type predicateNode struct {
+	Op      symbol
+	Keyword string
+	Field   AstNode
+}
+
+func newPredicateNode(op symbol, keyword string, field AstNode) (*predicateNode, error) {
+	n := &predicateNode{Op: op, Keyword: keyword, Field: field}
+	if err := n.stateErr(); err != nil {
+		return nil, err
+	}
+	if _, err := fieldName(field); err != nil {
+		return nil, newSyntaxError(keyword + " requires a field")
+	}
+	return n, nil
+}
+
+func (n *predicateNode) Format(args FormatArgs) error {
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+	keyword := args.Format.Keyword(n.Keyword)
+	if keyword == "" {
+		return newUnsupportedError(n.Keyword)
+	}
+
+	newArgs := args
+	newArgs.Ctx = NoFormatContext
+	if n.Op == existsToken {
+		args.Writer.WriteString(keyword)
+		args.Writer.WriteString("(")
+		err := n.Field.Format(newArgs)
+		args.Writer.WriteString(")")
+		return err
+	}
+	err := formatChild(n.Field, newArgs, operandNeedsParens(n.Field))
+	if err != nil {
+		return err
+	}
+	_, err = args.Writer.WriteString(keyword)
+	return err
+}
+
+func (n *predicateNode) Fields(args *FieldArgs) error {
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+
+	prevctx := args.Ctx
+	defer func() { args.Ctx = prevctx }()
+	args.Ctx = NoFormatContext
+	return n.Field.Fields(args)
+}
+
+func (n *predicateNode) Extract(fn any) error {
+	ep, ok := fn.(ExtractPredicate)
+	if !ok {
+		return nil
+	}
+	if err := n.stateErr(); err != nil {
+		return err
+	}
+	field, err := fieldName(n.Field)
+	if err != nil {
+		return err
+	}
+	return ep.FieldPredicate(field, n.Keyword)
+}
+
+func (n *predicateNode) Eval(args EvalArgs) (any, error) {
+	if err := n.stateErr(); err != nil {
+		return nil, err
+	}
+	// Missing fields are never an error here, since
+	// testing for them is the point.
+	path, _ := FieldOf(n.Field)
+	v, found := evalPath(args.Item, path)
+	switch n.Op {
+	case isNullToken:
+		return found && isNull(v), nil
+	case isNotNullToken:
+		return found && !isNull(v), nil
+	case existsToken:
+		return found, nil
+	}
+	return nil, newMalformedError("predicate node has unknown operator " + n.Keyword)
+}
+
+func (n *predicateNode) stateErr() error {
+	if n.Field == nil {
+		return newMalformedError("predicate node")
+	}
+	if n.Keyword == "" {
+		return newMalformedError("predicate node missing keyword")
+	}
+	return nil
+}
+
+// isNull answers true for nil and for nil pointers,
+// interfaces, maps and slices.
+func isNull(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -1,5 +1,9 @@
 package parser
 
+import (
+	"strings"
+)
+
 // ------------------------------------------------------------
 // TOKEN-T
 
@@ -8,6 +12,7 @@ type tokenT struct {
 	Symbol       symbol
 	Text         string
 	BindingPower int
+	Assoc        assoc
 	nud          nudFn
 	led          ledFn
 }
@@ -47,12 +52,30 @@ func emptyLed(n *nodeT, p *parserT, left *nodeT) (*nodeT, error) {
 
 func binaryLed(n *nodeT, p *parserT, left *nodeT) (*nodeT, error) {
 	n.addChild(left)
-	right, err := p.Expression(n.Token.BindingPower)
+	rbp := n.Token.BindingPower
+	if n.Token.Assoc == rightAssoc {
+		rbp--
+	}
+	right, err := p.Expression(rbp)
 	if err != nil {
 		return nil, err
 	}
 	n.addChild(right)
-	return n, nil
+	return n, checkChain(n, p)
+}
+
+// checkChain answers an error if n is non-associative and the
+// next token is another non-associative operator of the same
+// power, i.e. "a = b = c".
+func checkChain(n *nodeT, p *parserT) error {
+	if n.Token.Assoc != nonAssoc {
+		return nil
+	}
+	next := p.Peek()
+	if next.Token.BindingPower == n.Token.BindingPower && next.Token.Assoc == nonAssoc {
+		return errorAt(newSyntaxError(n.Text+" can't be chained with "+next.Text), next)
+	}
+	return nil
 }
 
 func enclosedNud(n *nodeT, p *parserT) (*nodeT, error) {
@@ -65,11 +88,226 @@ func enclosedNud(n *nodeT, p *parserT) (*nodeT, error) {
 		return nil, err
 	}
 	if next == nil {
-		return nil, newParseError("missing next for " + n.Text)
+		return nil, errorAt(newParseError("missing close for "+n.Text), p.illegal)
 	}
 	if next.Token.Symbol != closeToken {
-		return nil, newParseError("missing close for " + n.Text)
+		return nil, errorAt(newParseError("missing close for "+n.Text), next)
 	}
 	n.addChild(enclosed)
 	return n, nil
 }
+
+// inLed parses the parenthesized list on the RHS of a membership test.
+func inLed(n *nodeT, p *parserT, left *nodeT) (*nodeT, error) {
+	if p.Peek().Token.Symbol != openToken {
+		return nil, newSyntaxError(n.Text + " requires a parenthesized list")
+	}
+	return binaryLed(n, p, left)
+}
+
+// notLed handles NOT in the infix position, which is only
+// legal as the first half of NOT IN.
+func notLed(n *nodeT, p *parserT, left *nodeT) (*nodeT, error) {
+	next, err := p.Next()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil || next.Token.Symbol != inToken {
+		return nil, newSyntaxError("unexpected " + n.Text)
+	}
+	// Position the combined token at the NOT.
+	notIn := newNode(notInToken, n.Text+" "+next.Text)
+	notIn.Pos = n.Pos
+	return inLed(notIn, p, left)
+}
+
+// betweenLed parses BETWEEN a AND b, which is inclusive, and
+// the interval form BETWEEN [a, b), where a square bracket is an
+// inclusive bound and a parenthesis an exclusive one.
+func betweenLed(n *nodeT, p *parserT, left *nodeT) (*nodeT, error) {
+	n.addChild(left)
+	if !isInterval(p) {
+		lower, err := p.Expression(comparisonPower)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = expectNext(p, n.Text+" requires AND", andToken); err != nil {
+			return nil, err
+		}
+		upper, err := p.Expression(comparisonPower)
+		if err != nil {
+			return nil, err
+		}
+		n.addChild(lower)
+		n.addChild(upper)
+		return n, checkChain(n, p)
+	}
+
+	open, _ := p.Next()
+	lower, err := p.Expression(listPower)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = expectNext(p, n.Text+" interval requires two bounds", listToken); err != nil {
+		return nil, err
+	}
+	upper, err := p.Expression(listPower)
+	if err != nil {
+		return nil, err
+	}
+	closer, err := expectNext(p, "missing close for "+n.Text+" interval", closeToken, closeBracketToken)
+	if err != nil {
+		return nil, err
+	}
+	n.addChild(open)
+	n.addChild(lower)
+	n.addChild(upper)
+	n.addChild(closer)
+	return n, checkChain(n, p)
+}
+
+// isInterval answers true if the next token opens an interval.
+// A parenthesis only does if a comma follows at its own level,
+// so BETWEEN (1) AND 5 has a parenthesized bound.
+func isInterval(p *parserT) bool {
+	switch p.Peek().Token.Symbol {
+	case openBracketToken:
+		return true
+	case openToken:
+	default:
+		return false
+	}
+	depth := 0
+	for _, t := range p.tokens[p.position:] {
+		switch t.Token.Symbol {
+		case openToken, openBracketToken:
+			depth++
+		case closeToken, closeBracketToken:
+			depth--
+			if depth == 0 {
+				return false
+			}
+		case listToken:
+			if depth == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expectNext answers the next token, which must be one of symbols.
+func expectNext(p *parserT, msg string, symbols ...symbol) (*nodeT, error) {
+	next, err := p.Next()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, errorAt(newSyntaxError(msg), p.illegal)
+	}
+	if !next.Token.any(symbols...) {
+		return nil, errorAt(newSyntaxError(msg), next)
+	}
+	return next, nil
+}
+
+// prefixNud answers a nud that parses a single operand at
+// the supplied binding power, i.e. NOT and negation.
+func prefixNud(rbp int) nudFn {
+	return func(n *nodeT, p *parserT) (*nodeT, error) {
+		operand, err := p.Expression(rbp)
+		if err != nil {
+			return nil, err
+		}
+		n.addChild(operand)
+		return n, nil
+	}
+}
+
+// unexpectedLed is used by tokens that are only valid in
+// the prefix position.
+func unexpectedLed(n *nodeT, p *parserT, left *nodeT) (*nodeT, error) {
+	return nil, newSyntaxError("unexpected " + n.Text)
+}
+
+// valueLed handles a value that directly follows another expression,
+// i.e. "a b". This is an error, unless parsing is lenient, in which
+// case the value replaces the expression.
+func valueLed(n *nodeT, p *parserT, left *nodeT) (*nodeT, error) {
+	if p.opts.Lenient {
+		return n, nil
+	}
+	return nil, newSyntaxError("unexpected " + n.Text)
+}
+
+// identNud checks an identifier for a function call, which
+// is the function name followed directly by a parenthesized
+// argument list, i.e. "lower(name)".
+func identNud(n *nodeT, p *parserT) (*nodeT, error) {
+	open := p.Peek()
+	if open.Token.Symbol != openToken || strings.HasPrefix(n.Text, `"`) {
+		return n, nil
+	}
+	if !n.Pos.IsValid() || open.Pos.Offset != n.Pos.Offset+len(n.Text) {
+		return n, nil
+	}
+	p.Next()
+	call := newNode(callToken, n.Text)
+	call.Pos = n.Pos
+	if p.Peek().Token.Symbol == closeToken {
+		p.Next()
+		return call, nil
+	}
+	for {
+		arg, err := p.Expression(listPower)
+		if err != nil {
+			return nil, err
+		}
+		call.addChild(arg)
+		next, err := expectNext(p, "missing close for "+n.Text+"(", listToken, closeToken)
+		if err != nil {
+			return nil, err
+		}
+		if next.Token.Symbol == closeToken {
+			return call, nil
+		}
+	}
+}
+
+// isLed parses IS NULL and IS NOT NULL.
+func isLed(n *nodeT, p *parserT, left *nodeT) (*nodeT, error) {
+	text := n.Text
+	symbol := isNullToken
+	next, err := p.Next()
+	if err == nil && next != nil && next.Token.Symbol == notToken {
+		text += " " + next.Text
+		symbol = isNotNullToken
+		next, err = p.Next()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, errorAt(newSyntaxError(n.Text+" requires NULL"), p.illegal)
+	}
+	if next.Token.Symbol != nullToken {
+		return nil, errorAt(newSyntaxError(n.Text+" requires NULL"), next)
+	}
+	is := newNode(symbol, text+" "+next.Text)
+	is.Pos = n.Pos
+	is.addChild(left)
+	return is, checkChain(is, p)
+}
+
+// existsNud parses EXISTS(field).
+func existsNud(n *nodeT, p *parserT) (*nodeT, error) {
+	if p.Peek().Token.Symbol != openToken {
+		return nil, newSyntaxError(n.Text + " requires a parenthesized field")
+	}
+	operand, err := p.Expression(groupPower)
+	if err != nil {
+		return nil, err
+	}
+	n.addChild(operand)
+	return n, nil
+}
@@ -0,0 +1,412 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// ------------------------------------------------------------
+// TRANSFORM
+
+// Transform rewrites an AST, answering the new tree. Transforms
+// never modify their input, but the answer can share unchanged
+// subtrees with it.
+type Transform func(AstNode) (AstNode, error)
+
+// Chain answers a transform that applies each of ts in order.
+func Chain(ts ...Transform) Transform {
+	return func(n AstNode) (AstNode, error) {
+		var err error
+		for _, t := range ts {
+			if n, err = t(n); err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	}
+}
+
+// Normalize applies the standard canonicalization passes:
+// StripGroups, PushNot, FoldConstants, Flatten and Dedupe.
+func Normalize(n AstNode) (AstNode, error) {
+	return Chain(StripGroups, PushNot, FoldConstants, Flatten, Dedupe)(n)
+}
+
+// Rewrite applies fn to each node of n, bottom up, answering the
+// new tree. Nodes are copied when any of their children change.
+func Rewrite(n AstNode, fn func(AstNode) (AstNode, error)) (AstNode, error) {
+	if n == nil {
+		return nil, nil
+	}
+	kids := children(n)
+	if len(kids) > 0 {
+		changed := false
+		newKids := make([]AstNode, len(kids))
+		for i, kid := range kids {
+			newKid, err := Rewrite(kid, fn)
+			if err != nil {
+				return nil, err
+			}
+			newKids[i] = newKid
+			changed = changed || newKid != kid
+		}
+		if changed {
+			n = withChildren(n, newKids)
+		}
+	}
+	return fn(n)
+}
+
+// ------------------------------------------------------------
+// PASSES
+
+// StripGroups removes parentheses. Formatting adds back
+// any that are required by precedence.
+func StripGroups(n AstNode) (AstNode, error) {
+	return Rewrite(n, func(n AstNode) (AstNode, error) {
+		if u, ok := n.(*unaryNode); ok && u.Op == openToken {
+			return u.Child, nil
+		}
+		return n, nil
+	})
+}
+
+// PushNot moves NOT down to the leaves, applying De Morgan's
+// laws to AND and OR and removing double negation. Equality and
+// membership are inverted (= becomes !=, IN becomes NOT IN). Other
+// tests keep the NOT, since missing and mismatched values make
+// them false both ways, i.e. NOT a < 1 is not a >= 1.
+func PushNot(n AstNode) (AstNode, error) {
+	return pushNot(n, false), nil
+}
+
+func pushNot(n AstNode, negate bool) AstNode {
+	switch t := n.(type) {
+	case *unaryNode:
+		switch t.Op {
+		case notToken:
+			return pushNot(t.Child, !negate)
+		case openToken:
+			child := pushNot(t.Child, negate)
+			if child == t.Child {
+				return n
+			}
+			return &unaryNode{Op: openToken, Child: child}
+		}
+	case *binaryNode:
+		switch t.Op {
+		case andToken, orToken:
+			lhs, rhs := pushNot(t.Lhs, negate), pushNot(t.Rhs, negate)
+			op := t.Op
+			if negate && op == andToken {
+				op = orToken
+			} else if negate {
+				op = andToken
+			}
+			if op == t.Op && lhs == t.Lhs && rhs == t.Rhs {
+				return n
+			}
+			return newJoin(op, lhs, rhs)
+		}
+		if inverse, ok := inverseOps[t.Op]; ok && negate {
+			return &binaryNode{Op: inverse, Keyword: tokenMap[inverse].Text, Lhs: t.Lhs, Rhs: t.Rhs}
+		}
+	case *valueNode:
+		if b, ok := t.Value.(bool); ok && negate {
+			return &valueNode{Value: !b, Pos: t.Pos}
+		}
+	}
+	if negate {
+		return &unaryNode{Op: notToken, Keyword: NotKeyword, Child: n}
+	}
+	return n
+}
+
+var inverseOps = map[symbol]symbol{
+	assignToken: neqToken,
+	eqlToken:    neqToken,
+	neqToken:    eqlToken,
+	inToken:     notInToken,
+	notInToken:  inToken,
+}
+
+// FoldConstants evaluates operators whose operands are all
+// literals, and simplifies AND and OR with a boolean literal.
+// String literals are not folded, since they might be fields.
+func FoldConstants(n AstNode) (AstNode, error) {
+	return Rewrite(n, func(n AstNode) (AstNode, error) {
+		switch t := n.(type) {
+		case *unaryNode:
+			if t.Op == openToken {
+				if _, ok := constantOf(t.Child); ok {
+					return t.Child, nil
+				}
+				return n, nil
+			}
+		case *binaryNode:
+			switch t.Op {
+			case listToken:
+				return n, nil
+			case andToken, orToken:
+				if folded, ok := foldJoin(t); ok {
+					return folded, nil
+				}
+			}
+		default:
+			return n, nil
+		}
+		for _, kid := range children(n) {
+			if _, ok := constantOf(kid); !ok {
+				return n, nil
+			}
+		}
+		v, err := evalNode(n, EvalArgs{Opt: Opt{Strict: true}})
+		if err != nil {
+			// Leave errors to evaluation.
+			return n, nil
+		}
+		return &valueNode{Value: v}, nil
+	})
+}
+
+// foldJoin simplifies AND and OR with a boolean literal operand.
+// The other operand must be boolean too, since evaluating
+// "a AND true" converts a to a bool and "a" alone does not.
+func foldJoin(n *binaryNode) (AstNode, bool) {
+	// AND is absorbed by false, OR by true.
+	absorb := n.Op == orToken
+	for _, pair := range [][2]AstNode{{n.Lhs, n.Rhs}, {n.Rhs, n.Lhs}} {
+		v, ok := constantOf(pair[0])
+		b, isBool := v.(bool)
+		if !ok || !isBool || !isBoolean(pair[1]) {
+			continue
+		}
+		if b == absorb {
+			return pair[0], true
+		}
+		return pair[1], true
+	}
+	return nil, false
+}
+
+// isBoolean answers true if n always evaluates to a bool.
+func isBoolean(n AstNode) bool {
+	switch t := n.(type) {
+	case *unaryNode:
+		return t.Op == notToken || (t.Op == openToken && isBoolean(t.Child))
+	case *binaryNode:
+		return t.Op != listToken
+	case *matchNode, *betweenNode, *predicateNode:
+		return true
+	case *valueNode:
+		_, ok := t.Value.(bool)
+		return ok
+	}
+	return false
+}
+
+// Flatten rewrites chains of AND and OR, including chains broken
+// by parentheses, so they group to the left: a AND (b AND c)
+// becomes a AND b AND c.
+func Flatten(n AstNode) (AstNode, error) {
+	return Rewrite(n, func(n AstNode) (AstNode, error) {
+		b, ok := n.(*binaryNode)
+		if !ok || (b.Op != andToken && b.Op != orToken) || isLeftDeep(b) {
+			return n, nil
+		}
+		return newChain(b.Op, operands(n, b.Op)), nil
+	})
+}
+
+// isLeftDeep answers false if n continues its chain in the
+// RHS, or in a parenthesized LHS. Anything further down the
+// LHS has already been flattened.
+func isLeftDeep(n *binaryNode) bool {
+	if len(operands(n.Rhs, n.Op)) != 1 {
+		return false
+	}
+	u, ok := n.Lhs.(*unaryNode)
+	return !ok || u.Op != openToken || len(operands(u.Child, n.Op)) == 1
+}
+
+// Dedupe removes repeated operands from chains of AND and OR,
+// keeping the first, i.e. a AND b AND a becomes a AND b.
+// Operands with unbound placeholders are kept, since each
+// placeholder takes its own argument.
+func Dedupe(n AstNode) (AstNode, error) {
+	return Rewrite(n, func(n AstNode) (AstNode, error) {
+		b, ok := n.(*binaryNode)
+		if !ok || (b.Op != andToken && b.Op != orToken) {
+			return n, nil
+		}
+		ops := operands(n, b.Op)
+		seen := make(map[string]bool, len(ops))
+		unique := make([]AstNode, 0, len(ops))
+		for _, op := range ops {
+			if hasUnbound(op) {
+				unique = append(unique, op)
+				continue
+			}
+			key := nodeKey(op)
+			if !seen[key] {
+				seen[key] = true
+				unique = append(unique, op)
+			}
+		}
+		if len(unique) == len(ops) {
+			return n, nil
+		}
+		return newChain(b.Op, unique), nil
+	})
+}
+
+// ToDNF converts n to disjunctive normal form, an OR of ANDs.
+// The result can be exponentially larger than the input, so it
+// is an error if it would have more than normalFormLimit terms.
+func ToDNF(n AstNode) (AstNode, error) {
+	return toNormalForm(n, orToken, andToken)
+}
+
+// ToCNF converts n to conjunctive normal form, an AND of ORs.
+// The limits of ToDNF apply.
+func ToCNF(n AstNode) (AstNode, error) {
+	return toNormalForm(n, andToken, orToken)
+}
+
+const normalFormLimit = 1024
+
+func toNormalForm(n AstNode, outer, inner symbol) (AstNode, error) {
+	n, err := Chain(StripGroups, PushNot)(n)
+	if err != nil {
+		return nil, err
+	}
+	n, err = distribute(n, outer, inner)
+	if err != nil {
+		return nil, err
+	}
+	return Chain(Flatten, Dedupe)(n)
+}
+
+// distribute pushes inner operators below outer ones,
+// i.e. for DNF, a AND (b OR c) becomes a AND b OR a AND c.
+func distribute(n AstNode, outer, inner symbol) (AstNode, error) {
+	b, ok := n.(*binaryNode)
+	if !ok || (b.Op != outer && b.Op != inner) {
+		return n, nil
+	}
+	lhs, err := distribute(b.Lhs, outer, inner)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := distribute(b.Rhs, outer, inner)
+	if err != nil {
+		return nil, err
+	}
+	if b.Op == outer {
+		return newJoin(outer, lhs, rhs), nil
+	}
+	lterms, rterms := operands(lhs, outer), operands(rhs, outer)
+	if len(lterms)*len(rterms) > normalFormLimit {
+		return nil, newUnsupportedError(fmt.Sprintf("normal form exceeds %v terms", normalFormLimit))
+	}
+	terms := make([]AstNode, 0, len(lterms)*len(rterms))
+	for _, l := range lterms {
+		for _, r := range rterms {
+			terms = append(terms, newJoin(inner, l, r))
+		}
+	}
+	return newChain(outer, terms), nil
+}
+
+// ------------------------------------------------------------
+// SUPPORT
+
+// withChildren answers a copy of n with new children, which
+// parallel the answer from children().
+func withChildren(n AstNode, kids []AstNode) AstNode {
+	switch t := n.(type) {
+	case *binaryNode:
+		c := *t
+		c.Lhs, c.Rhs = kids[0], kids[1]
+		return &c
+	case *unaryNode:
+		c := *t
+		c.Child = kids[0]
+		return &c
+	case *listNode:
+		return &listNode{Items: kids}
+	case *matchNode:
+		c := *t
+		c.Lhs, c.Pattern = kids[0], kids[1]
+		return &c
+	case *predicateNode:
+		c := *t
+		c.Field = kids[0]
+		return &c
+	case *betweenNode:
+		c := *t
+		c.Field, c.Lower, c.Upper = kids[0], kids[1], kids[2]
+		return &c
+	case *callNode:
+		c := *t
+		c.Args = kids
+		return &c
+	}
+	return n
+}
+
+// hasUnbound answers true if n holds an unbound placeholder.
+func hasUnbound(n AstNode) bool {
+	found := false
+	Walk(n, func(n AstNode) error {
+		if p, ok := n.(*paramNode); ok && !p.Param.Bound {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
+// operands answers the operands of a chain of op, looking
+// through parentheses. Anything else is a single operand.
+func operands(n AstNode, op symbol) []AstNode {
+	switch t := n.(type) {
+	case *binaryNode:
+		if t.Op == op {
+			return append(operands(t.Lhs, op), operands(t.Rhs, op)...)
+		}
+	case *unaryNode:
+		if t.Op == openToken {
+			if inner := operands(t.Child, op); len(inner) > 1 {
+				return inner
+			}
+		}
+	}
+	return []AstNode{n}
+}
+
+// newJoin answers an AND or OR node.
+func newJoin(op symbol, lhs, rhs AstNode) *binaryNode {
+	return &binaryNode{Op: op, Keyword: tokenMap[op].Text, Lhs: lhs, Rhs: rhs}
+}
+
+// newChain answers operands joined by op, grouped to the left.
+func newChain(op symbol, operands []AstNode) AstNode {
+	n := operands[0]
+	for _, rhs := range operands[1:] {
+		n = newJoin(op, n, rhs)
+	}
+	return n
+}
+
+// constantOf answers the value of n, if it's a literal
+// that can't be a field.
+func constantOf(n AstNode) (any, bool) {
+	v, ok := n.(*valueNode)
+	if !ok {
+		return nil, false
+	}
+	if _, isString := v.Value.(string); isString {
+		return nil, false
+	}
+	return v.Value, true
+}
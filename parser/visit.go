@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// ------------------------------------------------------------
+// VISITOR
+
+// Visitor receives a read-only view of a single AST node. Use
+// Visit to dispatch a node to the matching method; visitors
+// that translate whole expressions call Visit on the children.
+//
+// Unquoted identifiers are values holding a string: they are
+// fields on the LHS of an operator, and strings on the RHS.
+// FieldOf answers the path for a node in field position.
+type Visitor interface {
+	// VisitBinary receives AND, OR, lists, assignment,
+	// comparisons, IN and NOT IN. The RHS of IN is a list.
+	VisitBinary(n BinaryNode) error
+	// VisitUnary receives NOT and negation.
+	VisitUnary(n UnaryNode) error
+	// VisitGroup receives a parenthesized expression.
+	VisitGroup(n GroupNode) error
+	VisitList(n ListNode) error
+	VisitValue(n ValueNode) error
+	VisitParam(p Param) error
+	VisitField(n FieldNode) error
+	// VisitMatch receives LIKE, STARTSWITH, CONTAINS and MATCHES.
+	VisitMatch(n MatchNode) error
+	// VisitPredicate receives IS NULL, IS NOT NULL and EXISTS.
+	VisitPredicate(n PredicateNode) error
+	VisitBetween(n BetweenNode) error
+	VisitCall(n CallNode) error
+}
+
+// BinaryNode is an operator with two operands.
+type BinaryNode struct {
+	Keyword string
+	Lhs     AstNode
+	Rhs     AstNode
+}
+
+// UnaryNode is an operator with a single operand.
+type UnaryNode struct {
+	Keyword string // NotKeyword or "-"
+	Child   AstNode
+}
+
+// GroupNode is a parenthesized expression.
+type GroupNode struct {
+	Child AstNode
+}
+
+// ListNode is a parenthesized list of values.
+type ListNode struct {
+	Items []AstNode
+}
+
+// ValueNode is a literal value.
+type ValueNode struct {
+	Value any
+	Pos   Position
+}
+
+// FieldNode is a reference to a nested field, i.e. "a.b[0]".
+type FieldNode struct {
+	Path FieldPath
+	Pos  Position
+}
+
+// MatchNode is a pattern match.
+type MatchNode struct {
+	Keyword string
+	Lhs     AstNode
+	Pattern AstNode
+}
+
+// PredicateNode is a test for presence or null.
+type PredicateNode struct {
+	Keyword string
+	Field   AstNode
+}
+
+// BetweenNode is a range test. Bounds are inclusive
+// unless marked exclusive.
+type BetweenNode struct {
+	Keyword        string
+	Field          AstNode
+	Lower          AstNode
+	Upper          AstNode
+	LowerExclusive bool
+	UpperExclusive bool
+}
+
+// CallNode is a function call.
+type CallNode struct {
+	Name string
+	Args []AstNode
+	Func Func
+}
+
+// Visit calls the method of v that matches n.
+func Visit(n AstNode, v Visitor) error {
+	switch t := n.(type) {
+	case *binaryNode:
+		return v.VisitBinary(BinaryNode{Keyword: t.Keyword, Lhs: t.Lhs, Rhs: t.Rhs})
+	case *unaryNode:
+		switch t.Op {
+		case openToken:
+			return v.VisitGroup(GroupNode{Child: t.Child})
+		case negToken:
+			return v.VisitUnary(UnaryNode{Keyword: "-", Child: t.Child})
+		}
+		return v.VisitUnary(UnaryNode{Keyword: t.Keyword, Child: t.Child})
+	case *listNode:
+		return v.VisitList(ListNode{Items: t.Items})
+	case *valueNode:
+		return v.VisitValue(ValueNode{Value: t.Value, Pos: t.Pos})
+	case *paramNode:
+		return v.VisitParam(t.Param)
+	case *fieldNode:
+		return v.VisitField(FieldNode{Path: t.Path, Pos: t.Pos})
+	case *matchNode:
+		return v.VisitMatch(MatchNode{Keyword: t.Keyword, Lhs: t.Lhs, Pattern: t.Pattern})
+	case *predicateNode:
+		return v.VisitPredicate(PredicateNode{Keyword: t.Keyword, Field: t.Field})
+	case *betweenNode:
+		return v.VisitBetween(BetweenNode{Keyword: t.Keyword, Field: t.Field, Lower: t.Lower, Upper: t.Upper, LowerExclusive: t.LowerExclusive, UpperExclusive: t.UpperExclusive})
+	case *callNode:
+		return v.VisitCall(CallNode{Name: t.Name, Args: t.Args, Func: t.fn})
+	}
+	return newUnhandledError(fmt.Sprintf("visit on %T", n))
+}
+
+// FieldOf answers the path of n, which is in field position.
+func FieldOf(n AstNode) (FieldPath, bool) {
+	switch t := n.(type) {
+	case *fieldNode:
+		return t.Path, true
+	case *valueNode:
+		if s, ok := t.Value.(string); ok {
+			return FieldPath{{Name: s}}, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"errors"
+)
+
+// SkipChildren can be returned from a Walk function
+// to skip the children of the current node.
+var SkipChildren = errors.New("skip children")
+
+// Walk calls fn on n and then on each of its descendants,
+// depth first and in order of appearance. Walking stops on
+// the first error other than SkipChildren.
+func Walk(n AstNode, fn func(AstNode) error) error {
+	if n == nil {
+		return nil
+	}
+	if err := fn(n); err == SkipChildren {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, child := range children(n) {
+		if err := Walk(child, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// childContexts answers the context of each of the children
+// of n, given the context of n. This follows formatting, where
+// the operand of a comparison is a field and the RHS a value.
+func childContexts(n AstNode, ctx FormatContext) []FormatContext {
+	kids := children(n)
+	ctxs := make([]FormatContext, len(kids))
+	for i := range ctxs {
+		ctxs[i] = ctx
+	}
+	switch t := n.(type) {
+	case *binaryNode:
+		ctxs[0], ctxs[1] = t.lhsContext(ctx), t.rhsContext(ctx)
+	case *matchNode:
+		ctxs[0], ctxs[1] = NoFormatContext, ValueContext
+	case *predicateNode:
+		ctxs[0] = NoFormatContext
+	case *betweenNode:
+		ctxs[0], ctxs[1], ctxs[2] = NoFormatContext, ValueContext, ValueContext
+	}
+	return ctxs
+}
+
+// children answers the direct children of n.
+func children(n AstNode) []AstNode {
+	switch t := n.(type) {
+	case *binaryNode:
+		return []AstNode{t.Lhs, t.Rhs}
+	case *unaryNode:
+		return []AstNode{t.Child}
+	case *listNode:
+		return t.Items
+	case *matchNode:
+		return []AstNode{t.Lhs, t.Pattern}
+	case *predicateNode:
+		return []AstNode{t.Field}
+	case *betweenNode:
+		return []AstNode{t.Field, t.Lower, t.Upper}
+	case *callNode:
+		return t.Args
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// FieldPath is a reference into a nested document,
+// i.e. "address.city" or "tags[0]".
+type FieldPath = parser.FieldPath
+
+// PathSegment is a single step in a FieldPath.
+type PathSegment = parser.PathSegment
+
+// PathFormat is an optional extension to Format
+// for rendering field paths.
+type PathFormat = parser.PathFormat
+
+// IdentifierFormat is an optional extension to Format
+// for quoting field names.
+type IdentifierFormat = parser.IdentifierFormat
+
+// ParseFieldPath converts a field name, as supplied to the
+// Extract interfaces and Validator, into a FieldPath.
+func ParseFieldPath(s string) (FieldPath, error) {
+	return parser.ParseFieldPath(s)
+}
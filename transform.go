@@ -0,0 +1,39 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// Transform rewrites an expression tree. See Normalize().
+type Transform = parser.Transform
+
+// The transforms available to Normalize(). See the parser
+// package for details.
+var (
+	StripGroups   Transform = parser.StripGroups
+	PushNot       Transform = parser.PushNot
+	FoldConstants Transform = parser.FoldConstants
+	Flatten       Transform = parser.Flatten
+	Dedupe        Transform = parser.Dedupe
+	ToDNF         Transform = parser.ToDNF
+	ToCNF         Transform = parser.ToCNF
+)
+
+// Normalize answers a new expression with the transforms applied
+// to the tree of e. With no transforms, the standard canonicalization
+// (parser.Normalize) is applied.
+func Normalize(e Expr, ts ...Transform) (Expr, error) {
+	c, err := compile(e)
+	if err != nil {
+		return nil, err
+	}
+	t := parser.Transform(parser.Normalize)
+	if len(ts) > 0 {
+		t = parser.Chain(ts...)
+	}
+	ast, err := t(c.ast)
+	if err != nil {
+		return nil, err
+	}
+	return newCompiledExpr(ast, c.f)
+}
@@ -1,7 +1,108 @@
 package doc
 
+import (
+	"reflect"
+	"strings"
+
+	"github.com/hackborn/doc/parser"
+)
+
 // Validator is used to validate an expression.
 type Validator interface {
 	// Return true if the field name is valid.
 	AcceptField(name string) bool
 }
+
+// FieldRef describes a field referenced in an expression.
+type FieldRef = parser.FieldRef
+
+// FieldError is returned when a Validator rejects
+// one or more fields in an expression.
+type FieldError struct {
+	Fields []FieldRef
+}
+
+func (e *FieldError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("doc: invalid fields (")
+	for i, f := range e.Fields {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(f.Name + " at " + f.Pos.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// validateFields answers a FieldError for every
+// field v rejects. v can be nil.
+func validateFields(v Validator, fields []FieldRef) error {
+	if v == nil {
+		return nil
+	}
+	var rejected []FieldRef
+	for _, f := range fields {
+		if !v.AcceptField(f.Name) {
+			rejected = append(rejected, f)
+		}
+	}
+	if len(rejected) > 0 {
+		return &FieldError{Fields: rejected}
+	}
+	return nil
+}
+
+// NewStructValidator answers a Validator that accepts exactly
+// the exported fields of struct T, including embedded structs and
+// their promoted fields, as Eval finds them. Field paths are
+// checked against nested types: struct fields by name, indexes
+// into slices and arrays, and any key into maps with string keys.
+func NewStructValidator[T any]() Validator {
+	return &structValidator{t: reflect.TypeOf((*T)(nil)).Elem()}
+}
+
+// structValidator accepts the fields of a struct.
+type structValidator struct {
+	t reflect.Type
+}
+
+func (v *structValidator) AcceptField(name string) bool {
+	path, err := parser.ParseFieldPath(name)
+	if err != nil {
+		return false
+	}
+	t := v.t
+	for _, seg := range path {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Interface:
+			// Anything could be stored here.
+			return true
+		case reflect.Struct:
+			if seg.IsIndex {
+				return false
+			}
+			f, ok := t.FieldByName(seg.Name)
+			if !ok || !f.IsExported() {
+				return false
+			}
+			t = f.Type
+		case reflect.Slice, reflect.Array:
+			if !seg.IsIndex || (t.Kind() == reflect.Array && seg.Index >= t.Len()) {
+				return false
+			}
+			t = t.Elem()
+		case reflect.Map:
+			if seg.IsIndex || t.Key().Kind() != reflect.String {
+				return false
+			}
+			t = t.Elem()
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package doc
+
+import (
+	"github.com/hackborn/doc/parser"
+)
+
+// AstNode is a node in a compiled expression. See Ast().
+type AstNode = parser.AstNode
+
+// Visitor receives a read-only view of a single AST node.
+type Visitor = parser.Visitor
+
+// Read-only views of the AST nodes, supplied to the Visitor.
+type (
+	BinaryNode    = parser.BinaryNode
+	UnaryNode     = parser.UnaryNode
+	GroupNode     = parser.GroupNode
+	ListNode      = parser.ListNode
+	ValueNode     = parser.ValueNode
+	FieldNode     = parser.FieldNode
+	MatchNode     = parser.MatchNode
+	PredicateNode = parser.PredicateNode
+	BetweenNode   = parser.BetweenNode
+	CallNode      = parser.CallNode
+)
+
+// SkipChildren can be returned from a Walk function
+// to skip the children of the current node.
+var SkipChildren = parser.SkipChildren
+
+// Visit calls the method of v that matches n.
+func Visit(n AstNode, v Visitor) error {
+	return parser.Visit(n, v)
+}
+
+// Walk calls fn on n and then on each of its descendants,
+// depth first and in order of appearance.
+func Walk(n AstNode, fn func(AstNode) error) error {
+	return parser.Walk(n, fn)
+}
+
+// FieldOf answers the path of n, which is in field position,
+// i.e. the LHS of a comparison.
+func FieldOf(n AstNode) (FieldPath, bool) {
+	return parser.FieldOf(n)
+}